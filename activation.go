@@ -0,0 +1,81 @@
+package fork
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// systemd socket activation (see sd_listen_fds(3))
+const (
+	listenFdsStart   = 3
+	listenPidVar     = "LISTEN_PID"
+	listenFdsVar     = "LISTEN_FDS"
+	listenFdNamesVar = "LISTEN_FDNAMES"
+	activationVar    = "GOFORK_LISTEN"
+)
+
+// the socket-activated files, created once: another *os.File for the same descriptor would close it when collected
+var listen struct {
+	once  sync.Once
+	files []*os.File
+	names []string
+}
+
+// listenFiles returns the socket-activated files systemd passed to this process, in descriptor order.
+// If we were not socket activated, no files are returned.
+func listenFiles() ([]*os.File, []string) {
+	listen.once.Do(func() { listen.files, listen.names = readListenFiles() })
+	return listen.files, listen.names
+}
+
+// readListenFiles takes the socket-activated files from the environment
+func readListenFiles() (files []*os.File, names []string) {
+	if pid, err := strconv.Atoi(os.Getenv(listenPidVar)); err != nil || pid != os.Getpid() {
+		return
+	}
+	n, err := strconv.Atoi(os.Getenv(listenFdsVar))
+	if err != nil || n <= 0 {
+		return
+	}
+	if v := os.Getenv(listenFdNamesVar); v != "" {
+		names = strings.Split(v, ":")
+	}
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFdsStart+i)
+		if i < len(names) {
+			name = names[i]
+		}
+		files = append(files, os.NewFile(uintptr(listenFdsStart+i), name))
+	}
+	return
+}
+
+// passListenFiles hands our socket-activated files to the child.
+// They must be the first extra files so they land on the same descriptors (3, 4, ...) in the child.
+// LISTEN_PID can't be known until the child exists, so the child sets it itself in Init.
+func (f *Function) passListenFiles() {
+	files, names := listenFiles()
+	f.Command.Env = unsetEnv(f.Command.Env, listenPidVar)
+	f.Command.Env = unsetEnv(f.Command.Env, listenFdsVar)
+	f.Command.Env = unsetEnv(f.Command.Env, listenFdNamesVar)
+	if len(files) == 0 {
+		return
+	}
+	f.Command.ExtraFiles = append(append([]*os.File(nil), files...), f.Command.ExtraFiles...)
+	f.Command.Env = append(f.Command.Env, listenFdsVar+"="+strconv.Itoa(len(files)))
+	if len(names) > 0 {
+		f.Command.Env = append(f.Command.Env, listenFdNamesVar+"="+strings.Join(names, ":"))
+	}
+	f.Command.Env = append(f.Command.Env, activationVar+"=1")
+}
+
+// initListenFiles claims socket-activated files passed on by the parent for this process.
+func initListenFiles() {
+	if os.Getenv(activationVar) == "" {
+		return
+	}
+	os.Unsetenv(activationVar)
+	os.Setenv(listenPidVar, strconv.Itoa(os.Getpid()))
+}
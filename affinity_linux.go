@@ -0,0 +1,129 @@
+package fork
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// the kernel's default cpu_set_t, enough for 1024 CPUs
+type cpuMask [16]uint64
+
+// setAffinity pins all threads of the process to cpus, and sizes GOMAXPROCS to them, unless it was set explicitly.
+// Threads inherit affinity, so any threads created after this are pinned the same.
+func setAffinity(cpus []int) error {
+	var mask cpuMask
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= len(mask)*64 {
+			return fmt.Errorf("cpu affinity: no such cpu: %d", cpu)
+		}
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+	if err := pinThreads(&mask); err != nil {
+		return err
+	}
+	// the runtime sized GOMAXPROCS to the CPUs we could run on when it started;
+	// those of cpus that are offline, or outside our cpuset, are left out of the mask we got
+	if os.Getenv("GOMAXPROCS") == "" {
+		var got cpuMask
+		if _, _, e := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(got), uintptr(unsafe.Pointer(&got))); e == 0 {
+			n := 0
+			for _, w := range got {
+				n += bits.OnesCount64(w)
+			}
+			runtime.GOMAXPROCS(n)
+		}
+	}
+	return nil
+}
+
+// pinThreads sets the affinity of all threads of the process to mask
+func pinThreads(mask *cpuMask) error {
+	_, _, e := syscall.AllThreadsSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if e != syscall.ENOTSUP {
+		if e != 0 {
+			return fmt.Errorf("cpu affinity: %v", e)
+		}
+		return nil
+	}
+	// with cgo, we have to go through the threads ourselves
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return fmt.Errorf("cpu affinity: %v", err)
+	}
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		if _, _, e := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask))); e != 0 && e != syscall.ESRCH {
+			return fmt.Errorf("cpu affinity: %v", e)
+		}
+	}
+	return nil
+}
+
+// NUMANodes returns the CPUs of each NUMA node, in node order.
+// A machine without NUMA has one node.
+// To spread children across nodes, set CPUAffinity to each node in turn.
+func NUMANodes() (nodes [][]int, err error) {
+	dirs, err := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	if err != nil {
+		return
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dirs[i]), "node"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dirs[j]), "node"))
+		return a < b
+	})
+	for _, dir := range dirs {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUList(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		if len(cpus) > 0 {
+			nodes = append(nodes, cpus)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no NUMA nodes found")
+	}
+	return
+}
+
+// parseCPUList parses a kernel cpu list, e.g. 0-3,8-11
+func parseCPUList(s string) (cpus []int, err error) {
+	if s == "" {
+		return
+	}
+	for _, r := range strings.Split(s, ",") {
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("bad cpu list: %q", s)
+		}
+		b, err := strconv.Atoi(hi)
+		if err != nil || b < a {
+			return nil, fmt.Errorf("bad cpu list: %q", s)
+		}
+		for cpu := a; cpu <= b; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return
+}
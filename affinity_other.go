@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+var errNoAffinity = errors.New("cpu affinity is only supported on Linux")
+
+func setAffinity(cpus []int) error {
+	return errNoAffinity
+}
+
+// NUMANodes returns the CPUs of each NUMA node, in node order (Linux only).
+func NUMANodes() ([][]int, error) {
+	return nil, errNoAffinity
+}
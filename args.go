@@ -0,0 +1,208 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Arguments are encoded, in order, each preceded by a bool flagging nil values.
+// gob can't encode nil pointers at all, and doesn't distinguish nil maps and slices from empty ones.
+
+// encodeArgs encodes args for a function with type t, which they've been validated against
+func encodeArgs(enc *gob.Encoder, t reflect.Type, args []interface{}) (err error) {
+	defer recoverCodec("encode", &err)
+	for i, iv := range args {
+		v := reflect.ValueOf(iv)
+		if connTypes[t.In(i)] && !isNilValue(v) {
+			// connections are passed alongside the args, which only carry a reference to them
+			ref, ok := iv.(connRef)
+			if !ok {
+				return fmt.Errorf("encode arg %d: a %T can only be passed to a fork", i+1, iv)
+			}
+			if err = enc.Encode(false); err == nil {
+				err = enc.Encode(ref)
+			}
+			if err != nil {
+				return fmt.Errorf("encode arg %d: %w", i+1, err)
+			}
+			continue
+		}
+		if t.In(i).Kind() == reflect.Interface && v.IsValid() {
+			// encode as the interface type, so gob sends the concrete type along with it
+			iface := reflect.New(t.In(i)).Elem()
+			iface.Set(v)
+			v = iface
+		}
+		isNil := isNilValue(v)
+		if err = enc.Encode(isNil); err != nil {
+			return fmt.Errorf("encode arg %d: %w", i+1, err)
+		}
+		if isNil {
+			continue
+		}
+		if err = enc.EncodeValue(v); err != nil {
+			return fmt.Errorf("encode arg %d: %w", i+1, err)
+		}
+	}
+	return
+}
+
+// decodeArgs decodes the arguments for a function of type t.
+// Connections are only decoded with conns set, as their references are taken to be descriptors passed to us, or held for us.
+func decodeArgs(dec *gob.Decoder, t reflect.Type, conns bool) (args []reflect.Value, err error) {
+	defer recoverCodec("decode", &err)
+	for i := 0; i < t.NumIn(); i++ {
+		var isNil bool
+		if err = dec.Decode(&isNil); err != nil {
+			return nil, fmt.Errorf("decode arg %d: %w", i+1, err)
+		}
+		v := reflect.New(t.In(i)).Elem()
+		if !isNil && connTypes[t.In(i)] {
+			var ref connRef
+			if err = dec.Decode(&ref); err != nil {
+				return nil, fmt.Errorf("decode arg %d: %w", i+1, err)
+			}
+			if !conns {
+				return nil, fmt.Errorf("decode arg %d: a %s can only be passed to a fork", i+1, t.In(i))
+			}
+			c, err := ref.conn()
+			if err != nil {
+				return nil, fmt.Errorf("decode arg %d: %w", i+1, err)
+			}
+			if !reflect.TypeOf(c).AssignableTo(t.In(i)) {
+				c.Close()
+				return nil, fmt.Errorf("decode arg %d: got a %T for %s", i+1, c, t.In(i))
+			}
+			v.Set(reflect.ValueOf(c))
+		} else if !isNil {
+			if err = dec.DecodeValue(v); err != nil {
+				return nil, fmt.Errorf("decode arg %d: %w", i+1, err)
+			}
+			// gob doesn't send empty slices and maps, but they weren't nil
+			switch {
+			case v.Kind() == reflect.Slice && v.IsNil():
+				v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			case v.Kind() == reflect.Map && v.IsNil():
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+		}
+		args = append(args, v)
+	}
+	return
+}
+
+// recoverCodec turns a panic while encoding or decoding args into an error.
+// gob is meant to return errors, but can still panic on some values (and malformed input).
+func recoverCodec(op string, err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%s args: panic: %v", op, r)
+	}
+}
+
+// EncodeArgs validates args against the signature of fn, then encodes them the way Fork would.
+func EncodeArgs(fn interface{}, args ...interface{}) ([]byte, error) {
+	f := NewFork("", fn)
+	if f == nil {
+		return nil, fmt.Errorf("not a function: %T", fn)
+	}
+	if err := f.validateArgs(args...); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encodeArgs(gob.NewEncoder(&buf), f.fn.Type(), args); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeArgs decodes arguments for fn, encoded by EncodeArgs, the way a child would.
+// Connections can't be, as EncodeArgs can't encode them: only nil is decoded for a connection parameter.
+func DecodeArgs(fn interface{}, data []byte) ([]interface{}, error) {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("not a function: %T", fn)
+	}
+	vs, err := decodeArgs(gob.NewDecoder(bytes.NewReader(data)), t, false)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(vs))
+	for i, v := range vs {
+		args[i] = v.Interface()
+	}
+	return args, nil
+}
+
+// RoundTrip passes args for fn through the same validate, encode and decode steps as a fork,
+// and returns the arguments the child would have been called with.
+// It is meant for tests and fuzzing of argument types.
+func RoundTrip(fn interface{}, args ...interface{}) ([]interface{}, error) {
+	data, err := EncodeArgs(fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeArgs(fn, data)
+}
+
+// nilable reports whether nil can be passed for a parameter of type t
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// isNilValue reports whether v is nil, or a nil pointer, slice, map or interface.
+// Pointers and interfaces are followed, as gob does: it can't send a pointer to a nil pointer, so that is nil too.
+func isNilValue(v reflect.Value) bool {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return true
+	}
+	if nilable(v.Type()) {
+		return v.IsNil()
+	}
+	return false
+}
+
+// countingWriter counts the bytes written through it.
+// If limit is set, the write that would go past it fails with ErrArgsTooLarge, so the encoder stops there.
+type countingWriter struct {
+	w     io.Writer
+	n     int64
+	limit int64
+}
+
+func (c *countingWriter) Write(p []byte) (n int, err error) {
+	if c.limit > 0 && c.n+int64(len(p)) > c.limit {
+		// the encoder stops at the first write that fails, so all we know is this much was asked for
+		return 0, fmt.Errorf("%w: encoded args of %s or more exceed limit %s", ErrArgsTooLarge, formatBytes(c.n+int64(len(p))), formatBytes(c.limit))
+	}
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return
+}
+
+// formatBytes formats n for humans, e.g. 1.2GB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	v := float64(n) / float64(div)
+	if v == float64(int64(v)) {
+		return fmt.Sprintf("%d%cB", int64(v), "KMGTPE"[exp])
+	}
+	return fmt.Sprintf("%.1f%cB", v, "KMGTPE"[exp])
+}
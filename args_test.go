@@ -0,0 +1,83 @@
+package fork
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type shape interface{ Area() int }
+
+type square struct{ Side int }
+
+func (s *square) Area() int { return s.Side * s.Side }
+
+func init() {
+	// interface arguments carry their concrete type, which gob must know
+	gob.Register(&square{})
+}
+
+func TestRoundTripNil(t *testing.T) {
+	n := 7
+	pn := &n
+	var nilPtr *int
+	var nilSquare *square
+	tests := []struct {
+		name string
+		fn   interface{}
+		args []interface{}
+		want []interface{}
+	}{
+		{"nil", func(*int) {}, []interface{}{nil}, []interface{}{nilPtr}},
+		{"typed nil pointer", func(*int) {}, []interface{}{nilPtr}, []interface{}{nilPtr}},
+		{"pointer", func(*int) {}, []interface{}{&n}, []interface{}{&n}},
+		{"pointer to pointer", func(**int) {}, []interface{}{&pn}, []interface{}{&pn}},
+		{"pointer to nil pointer", func(**int) {}, []interface{}{new(*int)}, []interface{}{(**int)(nil)}},
+		{"nil slice", func([]int) {}, []interface{}{[]int(nil)}, []interface{}{[]int(nil)}},
+		{"empty slice", func([]int) {}, []interface{}{[]int{}}, []interface{}{[]int{}}},
+		{"slice", func([]int) {}, []interface{}{[]int{1, 2}}, []interface{}{[]int{1, 2}}},
+		{"nil map", func(map[string]int) {}, []interface{}{map[string]int(nil)}, []interface{}{map[string]int(nil)}},
+		{"empty map", func(map[string]int) {}, []interface{}{map[string]int{}}, []interface{}{map[string]int{}}},
+		{"map", func(map[string]int) {}, []interface{}{map[string]int{"a": 1}}, []interface{}{map[string]int{"a": 1}}},
+		{"nil interface", func(shape) {}, []interface{}{nil}, []interface{}{nil}},
+		{"interface", func(shape) {}, []interface{}{&square{3}}, []interface{}{&square{3}}},
+		{"interface holding nil pointer", func(shape) {}, []interface{}{nilSquare}, []interface{}{nil}},
+		{"several", func(**int, []int, *int, map[string]int) {},
+			[]interface{}{new(*int), []int(nil), &n, map[string]int{"b": 2}},
+			[]interface{}{(**int)(nil), []int(nil), &n, map[string]int{"b": 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RoundTrip(tt.fn, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d args, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("arg %d: got %s, want %s", i+1, describe(got[i]), describe(tt.want[i]))
+				}
+			}
+		})
+	}
+}
+
+// describe formats v with its type, so nil and empty values can be told apart
+func describe(v interface{}) string {
+	return fmt.Sprintf("%#v", v)
+}
+
+func BenchmarkEncodeArgs(b *testing.B) {
+	payload := make([]byte, 16<<20)
+	fn := func([]byte) {}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeArgs(fn, payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
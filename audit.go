@@ -0,0 +1,87 @@
+package fork
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// An AuditRecord describes a fork when it starts (Event "fork") and when it has been waited for (Event "exit").
+type AuditRecord struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Name is the name of the fork
+	Name string `json:"name"`
+	// ArgDigest is the hex SHA-256 of the encoded arguments
+	ArgDigest string `json:"arg_digest"`
+	// Uid and Gid are the credentials the child runs with, if it started
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+	// SysProcAttr summarizes the attributes the child was started with, if it started
+	SysProcAttr string `json:"sys_proc_attr,omitempty"`
+	// Pid is the process id of the child, if it started
+	Pid int `json:"pid,omitempty"`
+	// Status is the exit status of the child, once it exited
+	Status string `json:"status,omitempty"`
+	// Err is the error returned by Fork or Wait, if any
+	Err string `json:"err,omitempty"`
+}
+
+var audit struct {
+	sync.Mutex
+	hook func(*AuditRecord)
+}
+
+// SetAuditHook sets a hook to be called with an AuditRecord every time a fork starts (or fails to), and every time one is waited for.
+// The hook is called synchronously from Fork and Wait, and must be safe for concurrent use. A nil hook disables auditing.
+func SetAuditHook(hook func(*AuditRecord)) {
+	audit.Lock()
+	audit.hook = hook
+	audit.Unlock()
+}
+
+// AuditLog returns an audit hook that writes records to w as JSON, one per line.
+func AuditLog(w io.Writer) func(*AuditRecord) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(r *AuditRecord) {
+		mu.Lock()
+		enc.Encode(r)
+		mu.Unlock()
+	}
+}
+
+func auditHook() func(*AuditRecord) {
+	audit.Lock()
+	defer audit.Unlock()
+	return audit.hook
+}
+
+// audit records event for f with the audit hook, if there is one
+func (f *Function) audit(event string, err error) {
+	hook := auditHook()
+	if hook == nil {
+		return
+	}
+	r := &AuditRecord{
+		Time:      time.Now(),
+		Event:     event,
+		Name:      f.Name,
+		ArgDigest: f.argDigest,
+	}
+	// a fork that failed may have been refused before it got to start, leaving those of the last child
+	if err == nil || event == "exit" {
+		r.Uid, r.Gid, r.SysProcAttr = describeSysProcAttr(f.Command.SysProcAttr)
+		if f.Command.Process != nil {
+			r.Pid = f.Command.Process.Pid
+		}
+	}
+	if ps := f.Command.ProcessState; ps != nil && event == "exit" {
+		r.Status = ps.String()
+	}
+	if err != nil {
+		r.Err = err.Error()
+	}
+	hook(r)
+}
@@ -0,0 +1,74 @@
+package fork
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var cloneFlagNames = []struct {
+	flag uintptr
+	name string
+}{
+	{syscall.CLONE_NEWNS, "NEWNS"},
+	{syscall.CLONE_NEWUTS, "NEWUTS"},
+	{syscall.CLONE_NEWIPC, "NEWIPC"},
+	{syscall.CLONE_NEWUSER, "NEWUSER"},
+	{syscall.CLONE_NEWPID, "NEWPID"},
+	{syscall.CLONE_NEWNET, "NEWNET"},
+	{0x02000000, "NEWCGROUP"},
+}
+
+// describeSysProcAttr returns the credentials a child started with attr runs as, and a summary of attr
+func describeSysProcAttr(attr *syscall.SysProcAttr) (uid, gid int, summary string) {
+	uid, gid = os.Getuid(), os.Getgid()
+	if attr == nil {
+		return
+	}
+	var s []string
+	if attr.Credential != nil {
+		uid, gid = int(attr.Credential.Uid), int(attr.Credential.Gid)
+		s = append(s, fmt.Sprintf("credential=%d:%d", uid, gid))
+	}
+	if f := flagNames(attr.Cloneflags); f != "" {
+		s = append(s, "clone="+f)
+	}
+	if f := flagNames(attr.Unshareflags); f != "" {
+		s = append(s, "unshare="+f)
+	}
+	if len(attr.UidMappings) > 0 || len(attr.GidMappings) > 0 {
+		s = append(s, fmt.Sprintf("uidmap=%v gidmap=%v", attr.UidMappings, attr.GidMappings))
+	}
+	if attr.Chroot != "" {
+		s = append(s, "chroot="+attr.Chroot)
+	}
+	if attr.Setsid {
+		s = append(s, "setsid")
+	}
+	if attr.Setpgid {
+		s = append(s, "setpgid")
+	}
+	if attr.Pdeathsig != 0 {
+		s = append(s, "pdeathsig="+attr.Pdeathsig.String())
+	}
+	if len(attr.AmbientCaps) > 0 {
+		s = append(s, fmt.Sprintf("ambientcaps=%v", attr.AmbientCaps))
+	}
+	summary = strings.Join(s, " ")
+	return
+}
+
+func flagNames(flags uintptr) string {
+	var names []string
+	for _, f := range cloneFlagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+			flags &^= f.flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("%#x", flags))
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// describeSysProcAttr returns the credentials a child started with attr runs as, and a summary of attr
+func describeSysProcAttr(attr *syscall.SysProcAttr) (uid, gid int, summary string) {
+	uid, gid = os.Getuid(), os.Getgid()
+	if attr != nil {
+		summary = fmt.Sprintf("%+v", *attr)
+	}
+	return
+}
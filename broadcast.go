@@ -0,0 +1,136 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"reflect"
+	"sync"
+)
+
+// the running children with a control channel, by Function name
+var running struct {
+	sync.Mutex
+	children map[string]map[*Function]*rpc.Client
+}
+
+// trackRunning records that f's child is running, and can be broadcast to
+func (f *Function) trackRunning() {
+	running.Lock()
+	defer running.Unlock()
+	if running.children == nil {
+		running.children = make(map[string]map[*Function]*rpc.Client)
+	}
+	if running.children[f.Name] == nil {
+		running.children[f.Name] = make(map[*Function]*rpc.Client)
+	}
+	running.children[f.Name][f] = f.control
+}
+
+// untrackRunning records that f's child has exited
+func (f *Function) untrackRunning() {
+	running.Lock()
+	defer running.Unlock()
+	delete(running.children[f.Name], f)
+	if len(running.children[f.Name]) == 0 {
+		delete(running.children, f.Name)
+	}
+}
+
+// ConfigUpdate is a configuration update as Broadcast sends it: the payload, gob encoded, and the name of its type
+type ConfigUpdate struct {
+	Type string
+	Data []byte
+}
+
+// configType names the type of a configuration update, or of the parameter of a handler for it.
+// Pointers are left out, as gob leaves them out, so a handler of *T is called with updates of T, and the other way round.
+func configType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// Broadcast delivers a configuration update to every running child of the Function name that has a control channel
+// (see ControlChannel), calling the handlers each registered with OnConfig for exactly the type of payload.
+// It waits for the handlers to return, and returns the first error a child reported.
+func Broadcast(name string, payload interface{}) error {
+	if payload == nil {
+		return errors.New("encode config: nil payload")
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(payload); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	update := &ConfigUpdate{Type: configType(reflect.TypeOf(payload)), Data: buf.Bytes()}
+	running.Lock()
+	var clients []*rpc.Client
+	for _, c := range running.children[name] {
+		clients = append(clients, c)
+	}
+	running.Unlock()
+	calls := make([]*rpc.Call, len(clients))
+	for i, c := range clients {
+		calls[i] = c.Go("GoFork.Config", update, new(bool), make(chan *rpc.Call, 1))
+	}
+	var err error
+	for _, call := range calls {
+		<-call.Done
+		if call.Error != nil && call.Error != rpc.ErrShutdown && err == nil {
+			// children that exited meanwhile don't count
+			err = call.Error
+		}
+	}
+	return err
+}
+
+// the configuration handlers of a child
+var (
+	configMu       sync.Mutex
+	configHandlers []reflect.Value
+)
+
+// OnConfig registers handler, a func taking a single configuration value of any type, to be called with the updates
+// the parent sends with Broadcast. Handlers are called one at a time, in the control channel's goroutine.
+// It is called by a child, and returns ErrNoControl if the child has no control channel.
+func OnConfig(handler interface{}) error {
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Func || v.Type().NumIn() != 1 {
+		return fmt.Errorf("config handler must be a func with one argument, not %T", handler)
+	}
+	if controlServer == nil {
+		return ErrNoControl
+	}
+	configMu.Lock()
+	configHandlers = append(configHandlers, v)
+	configMu.Unlock()
+	return nil
+}
+
+// Config calls the configuration handlers for the type of the update
+func (controlService) Config(update *ConfigUpdate, ok *bool) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	for _, h := range configHandlers {
+		t := h.Type().In(0)
+		if configType(t) != update.Type {
+			continue
+		}
+		v := reflect.New(t)
+		if err := gob.NewDecoder(bytes.NewReader(update.Data)).DecodeValue(v); err != nil {
+			return fmt.Errorf("decode config: %w", err)
+		}
+		h.Call([]reflect.Value{v.Elem()})
+		*ok = true
+	}
+	if !*ok {
+		return errors.New("no config handler for " + update.Type)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package fork
+
+import (
+	"net/rpc"
+	"sync"
+)
+
+// A Bus routes messages between children, by topic: children Publish, and the Bus delivers to the children that Subscribe.
+// The parent can publish on the Bus as well. Messages may be delivered out of order.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[string]map[*statusReader]*rpc.Client
+}
+
+// A Message is a message on a Bus, as delivered to a subscribed child
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// NewBus creates an empty Bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[*statusReader]*rpc.Client)}
+}
+
+// Attach connects the children of f to the bus, from its next Fork on.
+// Receiving messages needs a control channel, so this sets ControlChannel (not supported on Windows).
+func (b *Bus) Attach(f *Function) {
+	f.bus = b
+	f.ControlChannel = true
+}
+
+// Publish delivers payload to every child subscribed to topic
+func (b *Bus) Publish(topic string, payload []byte) {
+	b.publish(nil, topic, payload)
+}
+
+func (b *Bus) publish(from *statusReader, topic string, payload []byte) {
+	// sending may block on a child that is slow to read, which mustn't hold up subscribing and dropping
+	b.mu.Lock()
+	var clients []*rpc.Client
+	for s, c := range b.subs[topic] {
+		if s != from {
+			clients = append(clients, c)
+		}
+	}
+	b.mu.Unlock()
+	m := &Message{Topic: topic, Payload: payload}
+	for _, c := range clients {
+		// Go doesn't wait for the child to handle it, and a child that has gone away is dropped as its status pipe closes
+		c.Go("GoFork.Deliver", m, new(bool), make(chan *rpc.Call, 1))
+	}
+}
+
+// message handles a bus message a child sent over its status pipe
+func (b *Bus) message(from *statusReader, m *statusMessage) {
+	if !m.Subscribe {
+		b.publish(from, m.Topic, m.Payload)
+		return
+	}
+	if from.control == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[m.Topic] == nil {
+		b.subs[m.Topic] = make(map[*statusReader]*rpc.Client)
+	}
+	b.subs[m.Topic][from] = from.control
+}
+
+// drop forgets the subscriptions of a child, which has exited
+func (b *Bus) drop(s *statusReader) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for topic, subs := range b.subs {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(b.subs, topic)
+		}
+	}
+}
+
+// message handlers of a child, by topic
+var (
+	handlersMu sync.Mutex
+	handlers   = map[string][]func([]byte){}
+)
+
+// Publish sends payload to the children subscribed to topic on the parent's Bus.
+// It is called by a child, and returns ErrNoControl if it has no parent to send to.
+func Publish(topic string, payload []byte) error {
+	return sendStatus(&statusMessage{Topic: topic, Payload: payload})
+}
+
+// Subscribe has handler called with the payload of every message published on topic by the parent or other children.
+// It is called by a child, whose Function must be attached to a Bus; it returns ErrNoControl otherwise.
+func Subscribe(topic string, handler func(payload []byte)) error {
+	if controlServer == nil {
+		return ErrNoControl
+	}
+	handlersMu.Lock()
+	handlers[topic] = append(handlers[topic], handler)
+	first := len(handlers[topic]) == 1
+	handlersMu.Unlock()
+	if !first {
+		return nil
+	}
+	return sendStatus(&statusMessage{Topic: topic, Subscribe: true})
+}
+
+// Deliver calls the handlers the child subscribed to the message's topic with
+func (controlService) Deliver(m *Message, ok *bool) error {
+	handlersMu.Lock()
+	hs := handlers[m.Topic]
+	handlersMu.Unlock()
+	for _, h := range hs {
+		h(m.Payload)
+	}
+	*ok = true
+	return nil
+}
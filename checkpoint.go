@@ -0,0 +1,63 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+)
+
+// Checkpoint sends state to the parent, as the point a long running function has got to, so that if the child fails,
+// the parent can Resume from there. It is called by a child, as often as it sees fit; only the last state is kept.
+// state is gob encoded, and must decode into the type of the function's last parameter.
+// It returns ErrNoControl if there is no status pipe to the parent (e.g. on Windows).
+func Checkpoint(state interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return sendStatus(&statusMessage{Checkpoint: buf.Bytes()})
+}
+
+// LastCheckpoint decodes the last state a child of f sent with Checkpoint into the value state points to,
+// and reports whether there was one. It is kept until f is forked anew, other than by Resume.
+func (f *Function) LastCheckpoint(state interface{}) (bool, error) {
+	cp := f.getCheckpoint()
+	if cp == nil {
+		return false, nil
+	}
+	return true, gob.NewDecoder(bytes.NewReader(cp)).Decode(state)
+}
+
+// Resume forks f again, as ReFork does, with args followed by the last checkpoint of its previous child (see Checkpoint)
+// as the function's last argument, or its zero value if there is none, for a failed child to pick up where it left off.
+func (f *Function) Resume(args ...interface{}) (err error) {
+	t := f.fn.Type()
+	if t.NumIn() == 0 || len(args) != t.NumIn()-1 {
+		return fmt.Errorf("%w for resuming: %s", ErrBadArgCount, t.String())
+	}
+	cp := f.getCheckpoint()
+	state := reflect.New(t.In(t.NumIn() - 1))
+	if cp != nil {
+		if err = gob.NewDecoder(bytes.NewReader(cp)).DecodeValue(state); err != nil {
+			return fmt.Errorf("resume: %w", err)
+		}
+	}
+	// keep the checkpoint, in case the new child fails before it sends one of its own
+	f.resuming = true
+	err = f.ReFork(append(args, state.Elem().Interface())...)
+	f.resuming = false
+	return
+}
+
+func (f *Function) getCheckpoint() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkpoint
+}
+
+func (f *Function) setCheckpoint(cp []byte) {
+	f.mu.Lock()
+	f.checkpoint = cp
+	f.mu.Unlock()
+}
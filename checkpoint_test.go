@@ -0,0 +1,57 @@
+package fork
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// checkpointChild checkpoints two steps on from where it was resumed, then fails if asked to
+func checkpointChild(fail bool, from int) {
+	for i := from + 1; i <= from+2; i++ {
+		if err := Checkpoint(i); err != nil {
+			os.Exit(2)
+		}
+	}
+	if fail {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	RegisterFunc("checkpointChild", checkpointChild)
+}
+
+func TestCheckpointResume(t *testing.T) {
+	f := NewFork("checkpointChild", checkpointChild)
+	var state int
+	if ok, err := f.LastCheckpoint(&state); ok || err != nil {
+		t.Fatalf("LastCheckpoint before a fork: %v, %v", ok, err)
+	}
+	// resuming without a checkpoint starts from the zero value
+	if err := f.Resume(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(); err == nil {
+		t.Fatal("the child didn't fail")
+	}
+	if ok, err := f.LastCheckpoint(&state); !ok || err != nil || state != 2 {
+		t.Fatalf("LastCheckpoint: %d, %v, %v; want 2", state, ok, err)
+	}
+	if err := f.Resume(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := f.LastCheckpoint(&state); !ok || err != nil || state != 4 {
+		t.Fatalf("LastCheckpoint after Resume: %d, %v, %v; want 4", state, ok, err)
+	}
+}
+
+func TestResumeArgCount(t *testing.T) {
+	f := NewFork("checkpointChild", checkpointChild)
+	if err := f.Resume(true, 1); !errors.Is(err, ErrBadArgCount) {
+		t.Errorf("got %v, want ErrBadArgCount", err)
+	}
+}
@@ -0,0 +1,21 @@
+package fork
+
+import "reflect"
+
+// Function fields that belong to a child, rather than to how it is launched
+var notCloned = map[string]bool{"Command": true, "Process": true, "ProcessState": true}
+
+// clone returns a new Function with the launch configuration of f: all of its settings, and the path and args
+// the child is started with, but none of the state of its children
+func (f *Function) clone() *Function {
+	g := NewFork(f.Name, f.fn.Interface(), f.Command.Args...)
+	g.Command.Path = f.Command.Path
+	src, dst := reflect.ValueOf(f).Elem(), reflect.ValueOf(g).Elem()
+	t := src.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.PkgPath == "" && !notCloned[sf.Name] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return g
+}
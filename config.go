@@ -0,0 +1,123 @@
+package fork
+
+import (
+	"os"
+	"time"
+)
+
+// childConfig carries the settings a child applies to itself before calling its function.
+// It is encoded into the args file, ahead of the function arguments.
+type childConfig struct {
+	Pledge     string
+	Unveil     []UnveilPath
+	Jail       string
+	Capsicum   bool
+	Landlock   *LandlockRuleset
+	Mounts     []Mount
+	Network    *Network
+	Hostname   string
+	CPULimit   time.Duration
+	CoreDumps  CoreDumpMode
+	Management string
+	CPUs       []int
+	Title      string
+}
+
+// childConfig collects the child-side settings of f
+func (f *Function) childConfig() *childConfig {
+	mounts := f.Mounts
+	if f.PrivateTmp {
+		mounts = append(mounts[:len(mounts):len(mounts)], Mount{Type: "tmpfs", Target: os.TempDir(), Data: "mode=1777"})
+	}
+	return &childConfig{
+		Pledge:     f.Pledge,
+		Unveil:     f.Unveil,
+		Jail:       f.Jail,
+		Capsicum:   f.Capsicum,
+		Landlock:   f.Landlock,
+		Mounts:     mounts,
+		Network:    f.Network,
+		Hostname:   f.Hostname,
+		CPULimit:   f.CPULimit,
+		CoreDumps:  f.CoreDumps,
+		Management: f.ManagementSocket,
+		CPUs:       f.CPUAffinity,
+		Title:      f.Title,
+	}
+}
+
+// apply is called by the child once its arguments are decoded, just before the function runs
+func (c *childConfig) apply() (err error) {
+	// the socket must be reachable by the operator, so listen before any mounts or sandboxing
+	if c.Management != "" {
+		if err = serveManagement(c.Management); err != nil {
+			return
+		}
+	}
+	if len(c.Mounts) > 0 {
+		if err = mount(c.Mounts); err != nil {
+			return
+		}
+	}
+	if c.Jail != "" {
+		if err = jailAttach(c.Jail); err != nil {
+			return
+		}
+	}
+	if err = setCoreDumps(c.CoreDumps); err != nil {
+		return
+	}
+	if c.CPULimit > 0 {
+		if err = setCPULimit(c.CPULimit); err != nil {
+			return
+		}
+	}
+	if c.Title != "" {
+		// cosmetic, so not worth failing for
+		setComm(c.Title)
+	}
+	if len(c.CPUs) > 0 {
+		if err = setAffinity(c.CPUs); err != nil {
+			return
+		}
+	}
+	if c.Hostname != "" {
+		if err = sethostname(c.Hostname); err != nil {
+			return
+		}
+	}
+	if c.Network != nil {
+		if err = network(c.Network); err != nil {
+			return
+		}
+	}
+	if c.Landlock != nil {
+		if err = landlock(c.Landlock); err != nil {
+			return
+		}
+	}
+	// pledge and unveil restrict what the settings above may still need to do, so they come after them
+	for _, u := range c.Unveil {
+		if err = unveil(u.Path, u.Permissions); err != nil {
+			return
+		}
+	}
+	if len(c.Unveil) > 0 {
+		// lock the unveil list, nothing else can be unveiled after this
+		if err = unveil("", ""); err != nil {
+			return
+		}
+	}
+	if c.Pledge != "" {
+		if err = pledge(c.Pledge); err != nil {
+			return
+		}
+	}
+	// capability mode shuts off access to global namespaces, so it must come last
+	if c.Capsicum {
+		if err = capEnter(); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,139 @@
+package fork
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// parameter types we pass as connections, rather than encoding them
+var connTypes = map[reflect.Type]bool{
+	reflect.TypeOf((*net.Conn)(nil)).Elem(): true,
+	reflect.TypeOf((*net.TCPConn)(nil)):     true,
+	reflect.TypeOf((*net.UnixConn)(nil)):    true,
+}
+
+// connRef is encoded in place of a connection argument.
+// Fd is the descriptor the child inherits a copy of the connection on, or, for calls in this process, Ref refers to the connection in localConns.
+type connRef struct {
+	Fd  int
+	Ref uint64
+}
+
+// conns held for calls in this process, until their arguments are decoded
+var (
+	localConnsMu sync.Mutex
+	localConns   = map[uint64]net.Conn{}
+	localConnSeq uint64
+)
+
+// passConns replaces the connection arguments in args with connRefs, passing a copy of each to the child.
+// The returned files are our copies, to be closed once the child has started.
+func (f *Function) passConns(args []interface{}) (out []interface{}, files []*os.File, err error) {
+	t := f.fn.Type()
+	out = append([]interface{}(nil), args...)
+	for i, a := range args {
+		if !connTypes[t.In(i)] || isNilValue(reflect.ValueOf(a)) {
+			continue
+		}
+		fc, ok := a.(interface{ File() (*os.File, error) })
+		if !ok {
+			err = fmt.Errorf("arg %d: can't pass a %T to a child", i+1, a)
+			break
+		}
+		var file *os.File
+		if file, err = fc.File(); err != nil {
+			err = fmt.Errorf("arg %d: %w", i+1, err)
+			break
+		}
+		files = append(files, file)
+		out[i] = connRef{Fd: f.passFile(file)}
+	}
+	if err != nil {
+		closeFiles(files)
+		return nil, nil, err
+	}
+	return
+}
+
+// holdConns replaces the connection arguments in args with connRefs, for a call in this process.
+// Like a child, the function gets its own copy of each connection, so the caller is free to close theirs.
+func (f *Function) holdConns(args []interface{}) (out []interface{}, err error) {
+	t := f.fn.Type()
+	out = append([]interface{}(nil), args...)
+	localConnsMu.Lock()
+	defer localConnsMu.Unlock()
+	for i, a := range args {
+		if !connTypes[t.In(i)] || isNilValue(reflect.ValueOf(a)) {
+			continue
+		}
+		fc, ok := a.(interface{ File() (*os.File, error) })
+		if !ok {
+			err = fmt.Errorf("arg %d: can't pass a %T", i+1, a)
+			break
+		}
+		var file *os.File
+		if file, err = fc.File(); err != nil {
+			err = fmt.Errorf("arg %d: %w", i+1, err)
+			break
+		}
+		var c net.Conn
+		c, err = net.FileConn(file)
+		file.Close()
+		if err != nil {
+			err = fmt.Errorf("arg %d: %w", i+1, err)
+			break
+		}
+		localConnSeq++
+		localConns[localConnSeq] = c
+		out[i] = connRef{Ref: localConnSeq}
+	}
+	if err != nil {
+		releaseHeld(out)
+		return nil, err
+	}
+	return
+}
+
+// releaseConns closes the connections held for args, if they were never decoded
+func releaseConns(args []interface{}) {
+	localConnsMu.Lock()
+	defer localConnsMu.Unlock()
+	releaseHeld(args)
+}
+
+func releaseHeld(args []interface{}) {
+	for _, a := range args {
+		if r, ok := a.(connRef); ok && r.Ref != 0 {
+			if c, ok := localConns[r.Ref]; ok {
+				c.Close()
+				delete(localConns, r.Ref)
+			}
+		}
+	}
+}
+
+// conn rebuilds the connection r refers to
+func (r connRef) conn() (net.Conn, error) {
+	if r.Ref != 0 {
+		localConnsMu.Lock()
+		defer localConnsMu.Unlock()
+		c, ok := localConns[r.Ref]
+		if !ok {
+			return nil, fmt.Errorf("no connection held for ref %d", r.Ref)
+		}
+		delete(localConns, r.Ref)
+		return c, nil
+	}
+	file := os.NewFile(uintptr(r.Fd), "gofork-conn")
+	defer file.Close()
+	return net.FileConn(file)
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
@@ -0,0 +1,140 @@
+package fork
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"strconv"
+)
+
+// The control channel is gob encoded net/rpc, which has no versioning of its own: the child starts it with
+// controlMagic and the version of the control protocol it speaks, having been told ours in GOFORK_CONTROL_VERSION.
+// It isn't gRPC, as the package depends on nothing outside the standard library, nor on generated code; a child
+// that needs streaming or a protobuf API can serve one on a net.Conn passed to it as an argument.
+const (
+	controlVar        = "GOFORK_CONTROL"
+	controlVersionVar = "GOFORK_CONTROL_VERSION"
+	controlMagic      = "GFC"
+	controlVersion    = 1
+)
+
+// Control returns an RPC client connected to the child's control server, if the Function was forked with ControlChannel set.
+// Calls are served by whatever the child registered with ServeControl, for as long as the child runs, with gob encoded net/rpc.
+// If the child doesn't speak our version of the control protocol, the first calls fail with ErrControlVersion.
+// It returns nil otherwise.
+func (f *Function) Control() *rpc.Client {
+	return f.control
+}
+
+// openControl sets up the control channel to a child that's about to start.
+// The child's end is returned, to be closed once the child is started.
+func (f *Function) openControl() (child *os.File, err error) {
+	f.control = nil
+	ours, child, err := socketpair()
+	if err != nil {
+		return
+	}
+	conn, err := net.FileConn(ours)
+	ours.Close()
+	if err != nil {
+		child.Close()
+		return nil, err
+	}
+	f.Command.Env = append(f.Command.Env, controlVar+"="+strconv.Itoa(f.passFile(child)),
+		controlVersionVar+"="+strconv.Itoa(controlVersion))
+	f.control = rpc.NewClient(&controlConn{Conn: conn})
+	return
+}
+
+// controlConn is our end of the control channel, which checks that the child speaks our version before RPC goes over it
+type controlConn struct {
+	net.Conn
+	checked bool
+	err     error
+}
+
+// Read reads the header the child starts with, before anything else; net/rpc only reads from one goroutine
+func (c *controlConn) Read(p []byte) (int, error) {
+	if !c.checked {
+		c.checked = true
+		var h [len(controlMagic) + 1]byte
+		if _, err := io.ReadFull(c.Conn, h[:]); err != nil {
+			// the child exited, or is an older one that never says anything unasked
+			c.err = fmt.Errorf("%w: no control protocol header from the child: %v", ErrControlVersion, err)
+		} else if string(h[:len(controlMagic)]) != controlMagic {
+			c.err = fmt.Errorf("%w: no control protocol header from the child", ErrControlVersion)
+		} else if h[len(controlMagic)] != controlVersion {
+			c.err = fmt.Errorf("%w: child speaks version %d, not %d", ErrControlVersion, h[len(controlMagic)], controlVersion)
+		}
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.Conn.Read(p)
+}
+
+// closeControl closes our end of the control channel
+func (f *Function) closeControl() {
+	if f.control != nil {
+		f.control.Close()
+		f.control = nil
+	}
+}
+
+// the server for our end of the control channel, if we are a child with one
+var controlServer *rpc.Server
+
+// initControl starts serving the control channel from our parent, if there is one
+func initControl() {
+	v := os.Getenv(controlVar)
+	if v == "" {
+		return
+	}
+	os.Unsetenv(controlVar)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return
+	}
+	closeOnExec(fd)
+	file := os.NewFile(uintptr(fd), "gofork-control")
+	conn, err := net.FileConn(file)
+	file.Close()
+	if err != nil {
+		return
+	}
+	// a parent that doesn't tell us its version doesn't expect the header either
+	if v := os.Getenv(controlVersionVar); v != "" {
+		os.Unsetenv(controlVersionVar)
+		_, err = conn.Write(append([]byte(controlMagic), controlVersion))
+		if err != nil || v != strconv.Itoa(controlVersion) {
+			conn.Close()
+			return
+		}
+	}
+	controlServer = rpc.NewServer()
+	controlServer.RegisterName("GoFork", controlService{})
+	go controlServer.ServeConn(conn)
+}
+
+// controlService is served on the control channel of every child, as "GoFork"
+type controlService struct{}
+
+// ServeControl publishes the methods of rcvr to the parent over the control channel, as net/rpc's Register would.
+// It is called by a child, typically at the start of its function; the parent calls the methods through Function.Control.
+// Without a control channel (the Function wasn't forked with ControlChannel set), it returns ErrNoControl.
+func ServeControl(rcvr interface{}) error {
+	if controlServer == nil {
+		return ErrNoControl
+	}
+	return controlServer.Register(rcvr)
+}
+
+// ServeControlName is like ServeControl, but uses name for the service instead of the receiver's type name.
+func ServeControlName(name string, rcvr interface{}) error {
+	if controlServer == nil {
+		return ErrNoControl
+	}
+	return controlServer.RegisterName(name, rcvr)
+}
@@ -0,0 +1,188 @@
+package fork
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Coordinator lets cooperating processes, typically the children of one Function, elect a leader and partition work.
+// Members share a directory; each holds a lock on its own member file for as long as it is a member,
+// so members that die are dropped without any cleanup. Members are known by a random ID, as pids may be
+// the same for members in different pid namespaces.
+type Coordinator struct {
+	dir    string
+	id     string
+	member *os.File
+	mu     sync.Mutex
+	leader *os.File
+}
+
+// NewCoordinator joins the coordination group in dir, creating it if needed.
+// All members of a group must use the same dir, on a local filesystem.
+func NewCoordinator(dir string) (c *Coordinator, err error) {
+	members := filepath.Join(dir, "members")
+	if err = os.MkdirAll(members, 0700); err != nil {
+		return
+	}
+	// lock the member file before it has its name, so no one takes it for a stale one
+	tmp, err := ioutil.TempFile(members, ".join")
+	if err != nil {
+		return
+	}
+	b := make([]byte, 8)
+	if _, err = rand.Read(b); err == nil {
+		_, err = lockFile(tmp, true)
+	}
+	id := hex.EncodeToString(b)
+	if err == nil {
+		// the pid is only for people looking
+		if _, err = tmp.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err == nil {
+			err = os.Rename(tmp.Name(), filepath.Join(members, id))
+		}
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &Coordinator{dir: dir, id: id, member: tmp}, nil
+}
+
+// ID returns our member ID
+func (c *Coordinator) ID() string {
+	return c.id
+}
+
+// TryLead makes us the leader if there is no leader, and reports whether we are the leader
+func (c *Coordinator) TryLead() (bool, error) {
+	return c.lead(false)
+}
+
+// Lead waits until we are the leader.
+// Leadership passes on when the leader calls Close, or exits.
+func (c *Coordinator) Lead() error {
+	_, err := c.lead(true)
+	return err
+}
+
+func (c *Coordinator) lead(wait bool) (ok bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.leader != nil {
+		return true, nil
+	}
+	file, err := os.OpenFile(filepath.Join(c.dir, "leader"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	if ok, err = lockFile(file, wait); !ok || err != nil {
+		file.Close()
+		return false, err
+	}
+	c.leader = file
+	return
+}
+
+// IsLeader reports whether we are the leader
+func (c *Coordinator) IsLeader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leader != nil
+}
+
+// Members returns the IDs of the current members, in ascending order
+func (c *Coordinator) Members() (ids []string, err error) {
+	members := filepath.Join(c.dir, "members")
+	fis, err := ioutil.ReadDir(members)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	self := ""
+	if c.member != nil {
+		self = c.id
+	}
+	c.mu.Unlock()
+	for _, fi := range fis {
+		id := fi.Name()
+		if strings.HasPrefix(id, ".") {
+			// joining
+			continue
+		}
+		// our own lock is on another open file, so we would take it for someone else's
+		if id != self && !held(filepath.Join(members, id)) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return
+}
+
+// held reports whether the member file at path is locked by a live member, and removes it if not
+func held(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	ok, err := lockFile(file, false)
+	if err != nil || !ok {
+		return true
+	}
+	os.Remove(path)
+	return false
+}
+
+// Shard returns our position among the current members, and their number.
+// Members that split work by index see a consistent split as long as membership doesn't change.
+func (c *Coordinator) Shard() (index, count int, err error) {
+	ids, err := c.Members()
+	if err != nil {
+		return
+	}
+	index = sort.SearchStrings(ids, c.id)
+	if index == len(ids) || ids[index] != c.id {
+		return 0, 0, ErrNotMember
+	}
+	return index, len(ids), nil
+}
+
+// Owns reports whether the work item identified by key falls to us, given the current members
+func (c *Coordinator) Owns(key string) (bool, error) {
+	index, count, err := c.Shard()
+	if err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, ErrNotMember
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32()%uint32(count)) == index, nil
+}
+
+// Close gives up leadership, if we have it, and leaves the group
+func (c *Coordinator) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.leader != nil {
+		c.leader.Close()
+		c.leader = nil
+	}
+	if c.member == nil {
+		return nil
+	}
+	os.Remove(filepath.Join(c.dir, "members", c.id))
+	err := c.member.Close()
+	c.member = nil
+	return err
+}
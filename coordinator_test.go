@@ -0,0 +1,81 @@
+package fork
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCoordinator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofork-coord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var cs []*Coordinator
+	for i := 0; i < 3; i++ {
+		c, err := NewCoordinator(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		cs = append(cs, c)
+	}
+
+	// one leader at a time, and leadership passes on when it leaves
+	if ok, err := cs[0].TryLead(); !ok || err != nil {
+		t.Fatalf("first TryLead: %v, %v", ok, err)
+	}
+	if ok, err := cs[1].TryLead(); ok || err != nil {
+		t.Fatalf("TryLead with a leader: %v, %v", ok, err)
+	}
+	if !cs[0].IsLeader() || cs[1].IsLeader() {
+		t.Error("IsLeader disagrees with TryLead")
+	}
+
+	// every key falls to exactly one member
+	ids, err := cs[0].Members()
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("Members: %v, %v", ids, err)
+	}
+	seen := map[int]bool{}
+	for _, c := range cs {
+		index, count, err := c.Shard()
+		if err != nil || count != 3 || seen[index] {
+			t.Fatalf("Shard: %d, %d, %v", index, count, err)
+		}
+		seen[index] = true
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		owners := 0
+		for _, c := range cs {
+			ok, err := c.Owns(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				owners++
+			}
+		}
+		if owners != 1 {
+			t.Errorf("%q is owned by %d members", key, owners)
+		}
+	}
+
+	if err := cs[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := cs[1].TryLead(); !ok || err != nil {
+		t.Fatalf("TryLead after the leader left: %v, %v", ok, err)
+	}
+	if ids, err := cs[1].Members(); err != nil || len(ids) != 2 {
+		t.Fatalf("Members after one left: %v, %v", ids, err)
+	}
+	if _, _, err := cs[0].Shard(); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Shard after Close: got %v, want ErrNotMember", err)
+	}
+	if _, err := cs[0].Owns("a"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("Owns after Close: got %v, want ErrNotMember", err)
+	}
+}
@@ -0,0 +1,14 @@
+package fork
+
+// CoreDumpMode says whether a child may dump core
+type CoreDumpMode int
+
+const (
+	// CoreDumpsInherit leaves the child with our core dump limit
+	CoreDumpsInherit CoreDumpMode = iota
+	// CoreDumpsOff disables core dumps for the child
+	CoreDumpsOff
+	// CoreDumpsOn raises the child's core dump limit as far as it can go, and has the Go runtime
+	// dump core when it crashes (GOTRACEBACK=crash, unless GOTRACEBACK is set)
+	CoreDumpsOn
+)
@@ -0,0 +1,108 @@
+package fork
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// collectCore moves the core file a crashed child left behind into CoreDir, and returns its new path,
+// or why it couldn't.
+//
+// The kernel writes cores according to the system-wide core_pattern, so this only works when that is a plain
+// file name, which is resolved against the child's working directory; piped patterns (systemd-coredump, apport, ...)
+// and absolute paths are left alone.
+func (f *Function) collectCore() (string, error) {
+	ps := f.Command.ProcessState
+	if f.CoreDir == "" || ps == nil {
+		return "", nil
+	}
+	if ws, ok := ps.Sys().(syscall.WaitStatus); !ok || !ws.CoreDump() {
+		return "", nil
+	}
+	b, err := ioutil.ReadFile("/proc/sys/kernel/core_pattern")
+	if err != nil {
+		return "", err
+	}
+	pattern := strings.TrimSpace(string(b))
+	if pattern == "" || strings.HasPrefix(pattern, "|") || filepath.IsAbs(pattern) {
+		return "", fmt.Errorf("core_pattern %q isn't a file name in the working directory", pattern)
+	}
+	// the command name the kernel knows the child by, which the child's Title replaces
+	comm := filepath.Base(f.Command.Path)
+	if f.Title != "" {
+		comm = f.Title
+	}
+	name := expandCorePattern(pattern, ps.Pid(), comm)
+	if b, err := ioutil.ReadFile("/proc/sys/kernel/core_uses_pid"); err == nil && strings.TrimSpace(string(b)) == "1" && !strings.Contains(pattern, "%p") {
+		name += "." + strconv.Itoa(ps.Pid())
+	}
+	dir := f.Command.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	src := filepath.Join(dir, name)
+	if err := os.MkdirAll(f.CoreDir, 0700); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(f.CoreDir, strings.Replace(f.Name, string(filepath.Separator), "_", -1)+"."+strconv.Itoa(ps.Pid())+".core")
+	if err := moveFile(src, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// moveFile renames src to dst, or copies it there, should they be on different filesystems
+func moveFile(src, dst string) (err error) {
+	err = os.Rename(src, dst)
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EXDEV {
+		return
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(dst)
+		return
+	}
+	return os.Remove(src)
+}
+
+// expandCorePattern expands the core_pattern specifiers we know the value of, see core(5)
+func expandCorePattern(pattern string, pid int, exe string) string {
+	if len(exe) > 15 {
+		// %e is the comm name
+		exe = exe[:15]
+	}
+	r := strings.NewReplacer(
+		"%%", "%",
+		"%p", strconv.Itoa(pid),
+		"%P", strconv.Itoa(pid),
+		"%e", exe,
+		"%u", strconv.Itoa(os.Getuid()),
+		"%g", strconv.Itoa(os.Getgid()),
+		"%h", hostname(),
+	)
+	return r.Replace(pattern)
+}
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
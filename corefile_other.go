@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+// collectCore is Linux only
+func (f *Function) collectCore() (string, error) {
+	return "", nil
+}
@@ -0,0 +1,13 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fork
+
+import "errors"
+
+func setCoreDumps(mode CoreDumpMode) error {
+	if mode == CoreDumpsInherit {
+		return nil
+	}
+	return errors.New("core dump control is not supported on this platform")
+}
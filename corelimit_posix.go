@@ -0,0 +1,23 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fork
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setCoreDumps sets our RLIMIT_CORE for mode
+func setCoreDumps(mode CoreDumpMode) (err error) {
+	switch mode {
+	case CoreDumpsOff:
+		err = setrlimit(syscall.RLIMIT_CORE, 0)
+	case CoreDumpsOn:
+		err = raiseRlimit(syscall.RLIMIT_CORE)
+	}
+	if err != nil {
+		return fmt.Errorf("setrlimit core: %v", err)
+	}
+	return
+}
@@ -0,0 +1,18 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fork
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+func setCPULimit(limit time.Duration) error {
+	return errors.New("cpu limits are not supported on this platform")
+}
+
+func (f *Function) cpuLimitExceeded(ps *os.ProcessState) bool {
+	return false
+}
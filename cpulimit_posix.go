@@ -0,0 +1,32 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fork
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// setCPULimit limits our CPU time with RLIMIT_CPU.
+// Soft and hard limits are the same, so the kernel sends SIGKILL as soon as we run over.
+func setCPULimit(limit time.Duration) error {
+	secs := uint64((limit + time.Second - 1) / time.Second)
+	if err := setrlimit(syscall.RLIMIT_CPU, secs); err != nil {
+		return fmt.Errorf("setrlimit cpu %d: %v", secs, err)
+	}
+	return nil
+}
+
+// cpuLimitExceeded reports whether the child, which exited with ps, was killed for running over its CPULimit
+func (f *Function) cpuLimitExceeded(ps *os.ProcessState) bool {
+	sig, ok := f.exitSignal(ps)
+	if !ok || (sig != syscall.SIGKILL && sig != syscall.SIGXCPU) {
+		return false
+	}
+	// rlimits only have one second granularity
+	secs := (f.CPULimit + time.Second - 1) / time.Second * time.Second
+	return ps.UserTime()+ps.SystemTime() >= secs-10*time.Millisecond
+}
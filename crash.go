@@ -0,0 +1,97 @@
+package fork
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// how much of the end of a child's stderr we keep to find a crash in
+const crashTail = 64 << 10
+
+// the ways the Go runtime starts reporting a crash
+var crashMarkers = [][]byte{
+	[]byte("panic: "),
+	[]byte("fatal error: "),
+	[]byte("unexpected signal "),
+	[]byte("SIGQUIT: quit"),
+	[]byte("SIGABRT: abort"),
+}
+
+// CrashReport returns the panic or fatal error output of the last child, with its stack traces, if it crashed.
+// It is only available with CaptureCrash set, once Wait has returned; otherwise it is empty.
+func (f *Function) CrashReport() string {
+	if f.crash == nil || f.Command.ProcessState == nil || f.Command.ProcessState.Success() {
+		return ""
+	}
+	return f.crash.report()
+}
+
+// crashed reports whether the child, which exited unsuccessfully with ps, crashed rather than chose to exit:
+// it was killed by a signal, or its function panicked, as the child tells us, or its stderr shows with CaptureCrash
+func (f *Function) crashed(ps *os.ProcessState) bool {
+	if _, ok := f.exitSignal(ps); ok {
+		return true
+	}
+	if f.status != nil && f.status.panicked {
+		return true
+	}
+	return f.crash != nil && f.crash.report() != ""
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// report returns everything from the start of the last crash in the buffer, if there is one.
+// Nested panics are indented, so the last line starting with a marker is where the crash begins.
+func (t *tailBuffer) report() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := -1
+	for _, m := range crashMarkers {
+		i := bytes.LastIndex(t.buf, m)
+		if i > 0 && t.buf[i-1] != '\n' {
+			// only at the start of a line
+			continue
+		}
+		if i > start {
+			start = i
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return string(t.buf[start:])
+}
+
+// addWriter returns a writer that writes to both w and extra, where w may be unset
+func addWriter(w io.Writer, extra io.Writer) io.Writer {
+	if w == nil {
+		return extra
+	}
+	if f, ok := w.(*os.File); ok && f == nil {
+		return extra
+	}
+	return io.MultiWriter(w, extra)
+}
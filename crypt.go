@@ -0,0 +1,237 @@
+package fork
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+const keyVar = "GOFORK_KEY"
+
+// encrypted args are sealed in segments of this much plaintext, so neither side holds all of it, sealed and not, at once
+const argsSegment = 64 << 10
+
+// encryptArgs writes the args encoded by encode to w, sealed with AES-256-GCM under a new key.
+// The key goes to the child over a pipe, so it is never on disk, or in the environment.
+func (f *Function) encryptArgs(w io.Writer, encode func(*gob.Encoder) error) (err error) {
+	if runtime.GOOS == "windows" {
+		return errors.New("encrypted args are not supported on Windows")
+	}
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	defer wipe(key)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return
+	}
+	s, err := newSealer(w, gcm)
+	if err != nil {
+		return
+	}
+	if err = encode(gob.NewEncoder(s)); err != nil {
+		wipe(s.buf[:cap(s.buf)])
+		return
+	}
+	if err = s.close(); err != nil {
+		return
+	}
+	kr, kw, err := os.Pipe()
+	if err != nil {
+		return
+	}
+	// the key is much smaller than a pipe buffer, so this doesn't block
+	_, err = kw.Write(key)
+	kw.Close()
+	if err != nil {
+		kr.Close()
+		return
+	}
+	f.Command.Env = append(f.Command.Env, keyVar+"="+strconv.Itoa(f.passFile(kr)))
+	f.closeAfterStart = append(f.closeAfterStart, kr)
+	return
+}
+
+// Each segment is sealed with the nonce prefix the file starts with, followed by the segment number and
+// a flag marking the last segment, so segments can't be reordered, dropped or cut off without it showing.
+const noncePrefix = 7
+
+// sealer seals what is written to it segment by segment, writing the sealed segments to w
+type sealer struct {
+	w     io.Writer
+	gcm   cipher.AEAD
+	nonce []byte
+	buf   []byte
+	out   []byte
+	n     uint32
+}
+
+// newSealer returns a sealer writing to w, having written the nonce prefix of a new file
+func newSealer(w io.Writer, gcm cipher.AEAD) (*sealer, error) {
+	s := &sealer{w: w, gcm: gcm, nonce: make([]byte, gcm.NonceSize()), buf: make([]byte, 0, argsSegment)}
+	if _, err := rand.Read(s.nonce[:noncePrefix]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(s.nonce[:noncePrefix]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sealer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(s.buf) == argsSegment {
+			// only sealed once there's more, as the last segment is marked
+			if err := s.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		k := copy(s.buf[len(s.buf):argsSegment], p)
+		s.buf, p = s.buf[:len(s.buf)+k], p[k:]
+	}
+	return n, nil
+}
+
+// close seals the last segment
+func (s *sealer) close() error {
+	return s.seal(true)
+}
+
+func (s *sealer) seal(last bool) error {
+	if s.n == math.MaxUint32 {
+		return errors.New("encrypted args are too large")
+	}
+	segmentNonce(s.nonce, s.n, last)
+	s.n++
+	s.out = s.gcm.Seal(s.out[:0], s.nonce, s.buf, nil)
+	wipe(s.buf)
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(s.out)
+	return err
+}
+
+// opener reads the plaintext of the segments sealed by a sealer from r
+type opener struct {
+	r     *bufio.Reader
+	gcm   cipher.AEAD
+	nonce []byte
+	seg   []byte
+	plain []byte
+	next  []byte
+	n     uint32
+	done  bool
+}
+
+// newOpener returns an opener of the file a sealer wrote to r, having read its nonce prefix
+func newOpener(r io.Reader, gcm cipher.AEAD) (*opener, error) {
+	o := &opener{
+		r:     bufio.NewReaderSize(r, argsSegment+gcm.Overhead()),
+		gcm:   gcm,
+		nonce: make([]byte, gcm.NonceSize()),
+		seg:   make([]byte, argsSegment+gcm.Overhead()),
+	}
+	if _, err := io.ReadFull(o.r, o.nonce[:noncePrefix]); err != nil {
+		return nil, errors.New("encrypted args are truncated")
+	}
+	return o, nil
+}
+
+func (o *opener) Read(p []byte) (int, error) {
+	for len(o.next) == 0 {
+		if o.done {
+			return 0, io.EOF
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	k := copy(p, o.next)
+	o.next = o.next[k:]
+	return k, nil
+}
+
+// open opens the next segment
+func (o *opener) open() (err error) {
+	n, err := io.ReadFull(o.r, o.seg)
+	last := false
+	switch err {
+	case nil:
+		_, err = o.r.Peek(1)
+		last = err == io.EOF
+	case io.ErrUnexpectedEOF, io.EOF:
+		last = true
+	default:
+		return err
+	}
+	segmentNonce(o.nonce, o.n, last)
+	o.n++
+	wipe(o.plain)
+	if o.plain, err = o.gcm.Open(o.plain[:0], o.nonce, o.seg[:n], nil); err != nil {
+		return errors.New("encrypted args are corrupt or truncated")
+	}
+	o.next, o.done = o.plain, last
+	return nil
+}
+
+func segmentNonce(nonce []byte, n uint32, last bool) {
+	binary.BigEndian.PutUint32(nonce[noncePrefix:], n)
+	nonce[noncePrefix+4] = 0
+	if last {
+		nonce[noncePrefix+4] = 1
+	}
+}
+
+// argsReader returns a reader of the args in file, decrypting them if our parent sent us a key
+func argsReader(file *os.File) (io.Reader, error) {
+	v := os.Getenv(keyVar)
+	if v == "" {
+		return file, nil
+	}
+	os.Unsetenv(keyVar)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, errors.New("bad key descriptor: " + v)
+	}
+	kf := os.NewFile(uintptr(fd), "gofork-key")
+	key, err := ioutil.ReadAll(kf)
+	kf.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(key)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newOpener(file, gcm)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,114 @@
+package fork
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io/ioutil"
+	"testing"
+)
+
+func testGCM(t *testing.T) cipher.AEAD {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gcm
+}
+
+// seal seals plain as encryptArgs does
+func seal(t *testing.T, gcm cipher.AEAD, plain []byte) []byte {
+	var buf bytes.Buffer
+	s, err := newSealer(&buf, gcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// in uneven writes, as an encoder would
+	for p := plain; len(p) > 0; {
+		k := 1000
+		if k > len(p) {
+			k = len(p)
+		}
+		if _, err := s.Write(p[:k]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[k:]
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(gcm cipher.AEAD, sealed []byte) ([]byte, error) {
+	o, err := newOpener(bytes.NewReader(sealed), gcm)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(o)
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	gcm := testGCM(t)
+	for _, n := range []int{0, 1, argsSegment - 1, argsSegment, argsSegment + 1, 3*argsSegment + 5} {
+		plain := make([]byte, n)
+		rand.Read(plain)
+		got, err := open(gcm, seal(t, gcm, plain))
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("%d bytes: got back %d different bytes", n, len(got))
+		}
+	}
+}
+
+func TestSealTampering(t *testing.T) {
+	gcm := testGCM(t)
+	plain := make([]byte, 3*argsSegment+5)
+	sealed := seal(t, gcm, plain)
+	segment := argsSegment + gcm.Overhead()
+	// the sealed segments, after the nonce prefix
+	segments := func(s []byte) [][]byte {
+		var out [][]byte
+		for s = s[noncePrefix:]; len(s) > 0; {
+			k := segment
+			if k > len(s) {
+				k = len(s)
+			}
+			out = append(out, s[:k])
+			s = s[k:]
+		}
+		return out
+	}
+	join := func(segs ...[]byte) []byte {
+		return append(append([]byte(nil), sealed[:noncePrefix]...), bytes.Join(segs, nil)...)
+	}
+	segs := segments(sealed)
+	flipped := append([]byte(nil), sealed...)
+	flipped[noncePrefix+segment+10] ^= 1
+	tests := []struct {
+		name   string
+		sealed []byte
+	}{
+		{"flipped bit", flipped},
+		{"reordered", join(segs[1], segs[0], segs[2], segs[3])},
+		{"last segment dropped", join(segs[:3]...)},
+		{"segment dropped", join(segs[0], segs[2], segs[3])},
+		{"cut short", sealed[:len(sealed)-1]},
+		{"cut at a segment", sealed[:noncePrefix+segment]},
+		{"no segments", sealed[:noncePrefix]},
+		{"prefix cut short", sealed[:noncePrefix-1]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := open(gcm, tt.sealed); err == nil {
+				t.Error("opened without error")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package fork
+
+import "errors"
+
+var (
+	// ErrNotRegistered is returned for the name of a function that isn't registered
+	ErrNotRegistered = errors.New("no registered function by name")
+	// ErrBadArgCount is returned by Fork when it isn't given as many args as the function takes
+	ErrBadArgCount = errors.New("incorrect number of args")
+	// ErrArgType is returned by Fork when an arg doesn't fit the type of its parameter
+	ErrArgType = errors.New("argument mismatch")
+	// ErrChildStart matches the errors for a child that couldn't be started: the error from Fork when the process
+	// couldn't be, and the *ChildError from Wait when it failed before it could call its function
+	ErrChildStart = errors.New("child failed to start")
+	// ErrChildCrash matches the errors Wait returns for a child that was killed by a signal, or whose function panicked
+	// (the *exec.ExitError), and for a function forked in process that panicked. A child that exits unsuccessfully
+	// of its own accord doesn't match. Panics in other goroutines, and fatal errors, are only caught with CaptureCrash.
+	ErrChildCrash = errors.New("child crashed")
+	// ErrMemoryLimitExceeded is returned by Wait when the child was killed for exceeding its MemoryLimit
+	ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+	// ErrCPULimitExceeded is returned by Wait when the child was killed for exceeding its CPULimit
+	ErrCPULimitExceeded = errors.New("cpu limit exceeded")
+	// ErrArgsTooLarge is returned by Fork when the encoded arguments are larger than MaxArgBytes
+	ErrArgsTooLarge = errors.New("args too large")
+	// ErrRateLimited is returned by Fork when starting the child would exceed the rate set with SetSpawnRate
+	ErrRateLimited = errors.New("spawn rate limited")
+	// ErrShutdownTimeout is returned by Shutdown when the child didn't exit in time, and was killed
+	ErrShutdownTimeout = errors.New("shutdown timed out")
+	// ErrCanceled is the Err of a Scheduled fork that was canceled before it started
+	ErrCanceled = errors.New("scheduled fork canceled")
+	// ErrMisuse is wrapped by the errors Fork, ReFork and Wait return for calls out of order, with DetectMisuse(MisuseError)
+	ErrMisuse = errors.New("misuse")
+	// ErrNoHandshake is returned by Fork when a child started from another Executable didn't acknowledge its invocation,
+	// as one that doesn't link this package can't; it also matches ErrChildStart
+	ErrNoHandshake = errors.New("child didn't acknowledge invocation")
+	// ErrNotReady is returned by Ready when the child exited before it could call its function, without saying why
+	ErrNotReady = errors.New("child exited before it was ready")
+	// ErrControlVersion is returned by the first calls over a control channel to a child that doesn't speak our version
+	// of the control protocol, e.g. one built with an older version of this package (see Function.Executable)
+	ErrControlVersion = errors.New("control protocol version mismatch")
+	// ErrNoControl is returned to a child, by ServeControl, Publish, Subscribe and Checkpoint, when it has no control channel to its parent
+	ErrNoControl = errors.New("no control channel")
+	// ErrNotMember is returned by Coordinator.Shard and Owns when we aren't among the members, e.g. after Close
+	ErrNotMember = errors.New("not a member of the coordination group")
+)
+
+// kindError is err, which errors.Is also finds to be kind
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e *kindError) Error() string        { return e.err.Error() }
+func (e *kindError) Unwrap() error        { return e.err }
+func (e *kindError) Is(target error) bool { return target == e.kind }
@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fork
+
+import "os"
+
+// exitSignal finds no signals, children aren't killed by them here
+func (f *Function) exitSignal(ps *os.ProcessState) (os.Signal, bool) {
+	return nil, false
+}
@@ -0,0 +1,25 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fork
+
+import (
+	"os"
+	"syscall"
+)
+
+// exitSignal returns the signal that killed the child that exited with ps, if one did.
+// The init shim can't be killed by a signal of its own as PID 1 of its namespace, so it exits with 128 plus
+// the number of the one that killed the child instead: with InitShim set, those exit statuses count as the signal.
+func (f *Function) exitSignal(ps *os.ProcessState) (os.Signal, bool) {
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	switch {
+	case !ok:
+		return nil, false
+	case ws.Signaled():
+		return ws.Signal(), true
+	case f.InitShim && ws.Exited() && ws.ExitStatus() > 128 && ws.ExitStatus() <= 128+64:
+		return syscall.Signal(ws.ExitStatus() - 128), true
+	}
+	return nil, false
+}
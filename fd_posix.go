@@ -0,0 +1,11 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fork
+
+import "syscall"
+
+// closeOnExec keeps fd from being inherited by anything we exec
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
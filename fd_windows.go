@@ -0,0 +1,4 @@
+package fork
+
+// closeOnExec is a no-op; descriptors aren't passed to children on Windows
+func closeOnExec(fd int) {}
@@ -0,0 +1,86 @@
+package fork
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// A Fingerprint records exactly how a child was launched: its command line, environment, working directory,
+// limits and namespaces, so that a failed run can be reproduced later.
+type Fingerprint struct {
+	// Name is the name of the fork
+	Name string `json:"name"`
+	// Time is when the child was started
+	Time time.Time `json:"time"`
+	// Executable is the path of the executable, and ExecutableDigest its hex SHA-256, as first seen by this process
+	// if it recorded one (see VerifyExecutable), or else as Fingerprint first found it
+	Executable       string `json:"executable"`
+	ExecutableDigest string `json:"executable_digest,omitempty"`
+	// Args is the command line of the child, Args[0] included
+	Args []string `json:"args"`
+	// Env is the environment of the child, in order, recorded as is (secrets in it included)
+	Env []string `json:"env"`
+	// Dir is the working directory of the child
+	Dir string `json:"dir"`
+	// ArgDigest is the hex SHA-256 of the encoded arguments, and ArgBytes their size
+	ArgDigest string `json:"arg_digest"`
+	ArgBytes  int64  `json:"arg_bytes"`
+	// Uid and Gid are the credentials the child runs with
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+	// SysProcAttr summarizes the attributes, such as namespace flags, the child was started with
+	SysProcAttr string `json:"sys_proc_attr,omitempty"`
+	// Sandbox and Limits are the settings the child applied to itself
+	Sandbox Sandbox `json:"sandbox"`
+	Limits  Limits  `json:"limits"`
+	// Recording is the directory the run was recorded in, if it was (see RecordDir)
+	Recording string `json:"recording,omitempty"`
+}
+
+// Fingerprint returns the Fingerprint of the last child started, or nil if none was.
+func (f *Function) Fingerprint() *Fingerprint {
+	fp := f.fingerprint
+	if fp == nil {
+		return nil
+	}
+	if fp.ExecutableDigest == "" {
+		// recording it here would have VerifyExecutable trust whatever is on disk now
+		var ok bool
+		if fp.ExecutableDigest, ok = recordedExecutable(fp.Executable); !ok {
+			fp.ExecutableDigest, _ = fileDigest(fp.Executable)
+		}
+	}
+	return fp
+}
+
+// JSON returns fp as indented JSON
+func (fp *Fingerprint) JSON() ([]byte, error) {
+	return json.MarshalIndent(fp, "", "  ")
+}
+
+// takeFingerprint records how the child is about to be started
+func (f *Function) takeFingerprint() {
+	c := &f.Command
+	p := f.profile()
+	fp := &Fingerprint{
+		Name:       f.Name,
+		Time:       time.Now(),
+		Executable: c.Path,
+		Args:       append([]string(nil), c.Args...),
+		Env:        append([]string(nil), c.Env...),
+		Dir:        c.Dir,
+		ArgDigest:  f.argDigest,
+		ArgBytes:   f.argBytes,
+		Sandbox:    p.Sandbox,
+		Limits:     p.Limits,
+	}
+	if fp.Dir == "" {
+		fp.Dir, _ = os.Getwd()
+	}
+	fp.Uid, fp.Gid, fp.SysProcAttr = describeSysProcAttr(c.SysProcAttr)
+	if f.recording != nil {
+		fp.Recording = f.recording.dir
+	}
+	f.fingerprint = fp
+}
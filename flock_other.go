@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package fork
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(file *os.File, wait bool) (bool, error) {
+	return false, errors.New("coordination is not supported on this platform")
+}
@@ -1,13 +1,21 @@
 package fork
 
 import (
+	"crypto/sha256"
 	"encoding/gob"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/rpc"
 	"os"
 	"os/exec"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 // A Function struct describes a fork process.  Usually this is only used internally, but if you want a bit more control of the sub-process,
@@ -27,12 +35,199 @@ type Function struct {
 	Stdout *os.File
 	// Where to send stderr (default: os.Stderr)
 	Stderr *os.File
-	// Where to get stdin (default: os.Stdin)
+	// Where to get stdin; see StdinMux to share our stdin between children (default: os.Stdin)
 	Stdin *os.File
+	// StdoutStream and StderrStream, if set, are also written the child's stdout and stderr, as the child writes them (default: nil)
+	StdoutStream io.Writer
+	StderrStream io.Writer
+	// SocketActivation forwards systemd socket-activated listeners (LISTEN_FDS) to the child,
+	// keeping their descriptor order and rewriting LISTEN_PID to the child (default: false)
+	SocketActivation bool
+	// KillOnParentExit places the child in a Job Object that is killed, along with its descendants,
+	// when the parent exits (Windows only, see SysProcAttr.Pdeathsig on Linux) (default: false)
+	KillOnParentExit bool
+	// ProcessGroup starts the child in a process group of its own (on Windows, a Job Object), so KillTree reaches
+	// all of its descendants. Without it, KillTree finds them through /proc on Linux, and only kills the child elsewhere
+	// (default: false)
+	ProcessGroup bool
+	// Pledge holds the pledge(2) promises the child makes before calling the function (OpenBSD only) (default: none)
+	Pledge string
+	// Unveil lists the only paths the child may see, applied with unveil(2) before calling the function (OpenBSD only) (default: none)
+	Unveil []UnveilPath
+	// Jail is the jail, by jid or name, the child attaches to before calling the function (FreeBSD only) (default: none)
+	Jail string
+	// Capsicum puts the child in capability mode just before calling the function (FreeBSD only) (default: false)
+	Capsicum bool
+	// Landlock restricts the child's filesystem access before calling the function (Linux only) (default: none)
+	Landlock *LandlockRuleset
+	// Mounts are set up by the child, in a new mount namespace, before calling the function (Linux only) (default: none)
+	Mounts []Mount
+	// Network gives the child a new network namespace, with loopback up, before calling the function (Linux only) (default: none)
+	Network *Network
+	// InitShim starts the child in a new PID namespace, under a minimal init (PID 1) that reaps orphans
+	// and forwards signals, while the function runs as its child. A function killed by a signal makes the init exit
+	// with 128 plus its number, which Wait and Report take for the signal (Linux only) (default: false)
+	InitShim bool
+	// Hostname gives the child a new UTS namespace with this hostname (Linux only) (default: ours)
+	Hostname string
+	// PrivateTmp gives the child its own empty tmpfs on /tmp (really os.TempDir()), in a new mount namespace,
+	// which disappears with the child (Linux only) (default: false)
+	PrivateTmp bool
+	// MemoryLimit, if set, has the parent poll the child's resident set size and kill it if it exceeds this many bytes,
+	// in which case Wait returns ErrMemoryLimitExceeded (Linux only) (default: 0, no limit)
+	MemoryLimit uint64
+	// MemoryPollInterval is how often the MemoryLimit is checked (default: 100ms)
+	MemoryPollInterval time.Duration
+	// CPULimit is the CPU time (rounded up to whole seconds) the child may use before it is killed,
+	// in which case Wait returns ErrCPULimitExceeded (default: 0, no limit)
+	CPULimit time.Duration
+	// MaxArgBytes is the largest size the args file may be: the encoded arguments, the child's settings that come with them,
+	// and with EncryptArgs, the sealing overhead; larger fail Fork with ErrArgsTooLarge before the child is started (default: 0, no limit)
+	MaxArgBytes int64
+	// VerifyExecutable refuses to fork, with an *ExecutableChangedError, if the executable has changed on disk
+	// since the Function was registered (or first forked, if it never was) (default: false)
+	VerifyExecutable bool
+	// Executable, if set, is the binary to start the child from, in place of our own, looked up like exec.LookPath does.
+	// It must link this package, call Init, and register the function under the same name; an incompatible binary fails
+	// with a ChildError. Fork waits for the child to acknowledge its invocation (the function name and protocol version)
+	// over its status pipe, and kills it and fails with ErrNoHandshake if it doesn't within 10 seconds (not checked on Windows, which has no status pipe) (default: "")
+	Executable string
+	// RecordDir, if set, records every fork in a new directory below it: the encoded arguments, the child's output and
+	// its exit status, so that it can be re-run with Replay (default: none)
+	RecordDir string
+	// LogDir, if set, also writes the child's stdout and stderr to log files of their own below it, for every fork,
+	// named after the function and the time it forked; see Report.StdoutLogs (default: none)
+	LogDir string
+	// LogMaxBytes and LogMaxAge continue a log file in a new, numbered, one once it has grown past LogMaxBytes,
+	// or been written to for LogMaxAge (default: 0, no rotation)
+	LogMaxBytes int64
+	LogMaxAge   time.Duration
+	// LogRetention removes the log files of the function that are older than it from LogDir, as it forks (default: 0, keep them)
+	LogRetention time.Duration
+	// GOMAXPROCS sets GOMAXPROCS for the child (default: 0, inherit ours)
+	GOMAXPROCS int
+	// GOGC sets the GOGC percentage for the child, or turns the collector off if negative (default: 0, inherit ours)
+	GOGC int
+	// GOTRACEBACK sets GOTRACEBACK for the child; it defaults to "crash" with CoreDumpsOn, and "all" with CaptureCrash
+	// (default: inherit ours)
+	GOTRACEBACK string
+	// CaptureCrash keeps the end of the child's stderr, even if it is also sent elsewhere, so that if the child
+	// crashes its panic and stack traces are available from CrashReport (default: false)
+	CaptureCrash bool
+	// CoreDumps enables or disables core dumps for the child (default: CoreDumpsInherit)
+	CoreDumps CoreDumpMode
+	// CoreDir is where a core dumped by the child is moved to, as <name>.<pid>.core, when the system core_pattern
+	// leaves cores next to the process; the Report says why if it couldn't be (Linux only) (default: none, leave cores where they are)
+	CoreDir string
+	// ControlChannel gives the child an RPC control channel, over an inherited socket, for the lifetime of the child:
+	// the child publishes services with ServeControl, and we call them through Control (not supported on Windows) (default: false)
+	ControlChannel bool
+	// ManagementSocket is the path of a unix socket on which the child serves a JSON-RPC management endpoint,
+	// with the methods Fork.Health, Fork.Stats, Fork.SetLogLevel and Fork.Stop (see ManagementHooks).
+	// Any "%p" is replaced with the child's pid, as in Process (that of the init shim, with InitShim); a child fails to start on a socket another child is still serving on.
+	// A pledged child needs the "unix" promise to accept connections. (default: "")
+	ManagementSocket string
+	// WaitForSpawn makes Fork wait until the spawn rate limit (see SetSpawnRate) allows the child to start,
+	// rather than fail with ErrRateLimited (default: false)
+	WaitForSpawn bool
+	// CPUAffinity pins the child to these CPUs, with GOMAXPROCS set to as many unless the GOMAXPROCS variable is;
+	// see NUMANodes to place children on distinct nodes (Linux only) (default: nil)
+	CPUAffinity []int
+	// Foreground hands our controlling terminal to the child until it exits, as a shell does for a foreground job,
+	// so interactive functions get terminal signals and input; the terminal and its settings are restored after (default: false)
+	Foreground bool
+	// EncryptArgs encrypts the args file, with a key only the child is given; recordings of the child hold the
+	// encrypted args, and can't be replayed (not supported on Windows) (default: false)
+	EncryptArgs bool
+	// Env is added to the child's environment, as "key=value" entries overriding ours (default: none)
+	Env []string
+	// SecretArgs lists the parameters, by index, whose arguments are secrets, as if they were of a Secret type
+	// (see SecretString): they are kept out of recordings and the ArgDigest (default: none)
+	SecretArgs []int
+	// Title replaces argv[0] of the child, and its command name, so it shows as e.g. "myapp: worker image-resize" in ps and top
+	// (the command name is only set on Linux, where it is cut to 15 bytes; see also SetProcessTitle) (default: Command.Args[0])
+	Title string
+	// SELinuxLabel is the SELinux context the child runs in, e.g. "system_u:system_r:myapp_worker_t:s0" (Linux only) (default: ours)
+	SELinuxLabel string
+	// AppArmorProfile is the AppArmor profile the child runs confined by (Linux only) (default: ours)
+	AppArmorProfile string
 
 	// contains filtered or unexported fields
-	Command  exec.Cmd
-	fn reflect.Value
+	Command exec.Cmd
+	fn      reflect.Value
+	job     uintptr
+	done    chan struct{}
+	mu      sync.Mutex
+	killed  error
+	status  *statusReader
+	accounting
+	report      *Report
+	recording   *recording
+	local       chan error
+	crash       *tailBuffer
+	coreFile    string
+	coreErr     error
+	control     *rpc.Client
+	terminal    *terminal
+	fingerprint *Fingerprint
+	bus         *Bus
+	state       int32
+	exit        *exitWatch
+	logs        []*rotatingLog
+	// the last checkpoint of a child, which Resume keeps for the next one
+	checkpoint []byte
+	resuming   bool
+	// files the child inherits that we close once it has started
+	closeAfterStart []*os.File
+}
+
+// UnveilPath is a path, and its unveil(2) permissions (some of "rwxc"), to expose to a child.
+type UnveilPath struct {
+	Path        string
+	Permissions string
+}
+
+// LandlockRuleset lists the path hierarchies a child may use under Landlock; everything else is denied.
+// Kernels without Landlock support run the child unrestricted, unless Required is set.
+// Landlock needs to restrict every thread, which the Go runtime can't do in a binary using cgo.
+type LandlockRuleset struct {
+	// ReadOnly paths may be read and executed
+	ReadOnly []string
+	// ReadWrite paths may also be written, created and removed
+	ReadWrite []string
+	// Required fails the fork if Landlock is unavailable
+	Required bool
+}
+
+// Mount describes a filesystem for a child to mount in its own mount namespace.
+type Mount struct {
+	// Type is "bind" (the default), "tmpfs" or "proc"
+	Type string
+	// Source is the path to bind mount (ignored for other types)
+	Source string
+	// Target is where to mount, created if it doesn't exist
+	Target string
+	// ReadOnly mounts read-only
+	ReadOnly bool
+	// Data holds filesystem specific mount options, e.g. "size=64m" for tmpfs
+	Data string
+}
+
+// Network describes the network namespace a child is started in.
+type Network struct {
+	// Veth, if set, connects the child's namespace to ours with a veth pair
+	Veth *Veth
+}
+
+// Veth describes a veth pair linking a child's network namespace to the parent's.
+type Veth struct {
+	// HostName is the name of our end (default: gofork<pid>)
+	HostName string
+	// ChildName is the name of the child's end (default: eth0)
+	ChildName string
+	// HostAddr and ChildAddr are the CIDR addresses, e.g. "10.0.0.1/24", assigned to each end (default: none)
+	HostAddr  string
+	ChildAddr string
 }
 
 // NewFork createas and initializes a Fork
@@ -65,81 +260,387 @@ func NewFork(n string, fn interface{}, args ...string) (f *Function) {
 }
 
 // Fork starts a process and prepares it to call the defined fork
+//
+// Arguments for net.Conn, *net.TCPConn and *net.UnixConn parameters aren't encoded: the child gets its own copy of the connection (not supported on Windows).
 func (f *Function) Fork(args ...interface{}) (err error) {
-	if err = f.validateArgs(args...); err != nil {
+	// forks that are refused are audited too
+	defer func() { f.audit("fork", err) }()
+	if err = f.checkFork(); err != nil {
 		return
 	}
-	f.Command.Stderr = f.Stderr
-	f.Command.Stdout = f.Stdout
-	f.Command.Stdin = f.Stdin
-	f.Command.SysProcAttr = f.SysProcAttr
-	f.Command.Env = os.Environ()
-	f.Command.Env = append(f.Command.Env, nameVar+"="+f.Name)
-	af, err := ioutil.TempFile("", "gofork_*")
-	f.Command.Env = append(f.Command.Env, argsVar+"="+af.Name())
-	if err != nil {
+	f.argDigest = ""
+	if err = f.validateArgs(args...); err != nil {
 		return
 	}
-	enc := gob.NewEncoder(af)
-	for _, iv := range args {
-		enc.EncodeValue(reflect.ValueOf(iv))
+	if forcedInProcess() {
+		return f.forkLocal(args...)
 	}
-	af.Close()
-	if err = f.Command.Start(); err != nil {
-		return
+	return f.start(args...)
+}
+
+// executablePath returns the path of the binary to start the child from
+func (f *Function) executablePath() (string, error) {
+	if f.Executable == "" {
+		return f.Command.Path, nil
 	}
-	f.Process = f.Command.Process
-	return
+	return exec.LookPath(f.Executable)
 }
 
 // Combine NewFork and Fork with privious function configuration
 func (f *Function) ReFork(args ...interface{}) (err error) {
+	defer func() { f.audit("fork", err) }()
+	if err = f.checkFork(); err != nil {
+		return
+	}
+	f.argDigest = ""
 	previous := f.Command
 	f.Command = exec.Cmd{}
 	f.Command.Path, _ = os.Executable()
 	f.Command.Args = previous.Args
+	if err = f.validateArgs(args...); err != nil {
+		return
+	}
+	if forcedInProcess() {
+		return f.forkLocal(args...)
+	}
+	return f.start(args...)
+}
+
+// Wait provides a wrapper around exec.Cmd.Wait()
+func (f *Function) Wait() (err error) {
+	if err = f.checkWait(); err != nil {
+		return
+	}
+	atomic.StoreInt32(&f.state, stateIdle)
+	if f.local != nil {
+		// we forked in process
+		err = <-f.local
+		f.local = nil
+		f.audit("exit", err)
+		return
+	}
+	f.waitReaped()
+	err = f.Command.Wait()
+	f.releaseJob()
+	closeLogs(f.logs)
+	f.stopWatchExit()
+	f.restoreTerminal()
+	if f.control != nil {
+		f.untrackRunning()
+	}
+	f.closeControl()
+	f.coreFile, f.coreErr = f.collectCore()
+	if f.status != nil {
+		if serr := f.status.wait(); serr != nil {
+			err = serr
+		}
+	}
+	if ee, ok := err.(*exec.ExitError); ok && f.crashed(ee.ProcessState) {
+		err = &kindError{err: err, kind: ErrChildCrash}
+	}
+	f.buildReport()
+	defer func() {
+		f.stopRecording(err)
+		f.audit("exit", err)
+	}()
+	if killed := f.stopWatchdogs(); killed != nil {
+		return killed
+	}
+	if f.CPULimit > 0 && f.Command.ProcessState != nil && f.cpuLimitExceeded(f.Command.ProcessState) {
+		return fmt.Errorf("%w: used %v of %v", ErrCPULimitExceeded,
+			f.Command.ProcessState.UserTime()+f.Command.ProcessState.SystemTime(), f.CPULimit)
+	}
+	if err != nil {
+		return
+	}
+	f.ProcessState = f.Command.ProcessState
+	return
+}
+
+// private
+
+// start encodes the arguments and launches the child process
+func (f *Function) start(args ...interface{}) (err error) {
+	var files []*os.File
+	defer func() { closeFiles(files) }()
+	var redacted func(*gob.Encoder) error
+	if f.hasSecrets() {
+		redacted = func(enc *gob.Encoder) error {
+			if err := enc.Encode(f.childConfig()); err != nil {
+				return err
+			}
+			return encodeArgs(enc, f.fn.Type(), f.redact(args))
+		}
+	}
+	return f.launch(func(enc *gob.Encoder) (err error) {
+		if err = enc.Encode(f.childConfig()); err != nil {
+			return
+		}
+		if args, files, err = f.passConns(args); err != nil {
+			return
+		}
+		return encodeArgs(enc, f.fn.Type(), args)
+	}, redacted, nil)
+}
+
+// launch launches the child process, with the args file written by encode, or copied from payload.
+// If there are secrets in the args, redacted encodes them without, for the digest and recording.
+func (f *Function) launch(encode, redacted func(*gob.Encoder) error, payload io.Reader) (err error) {
+	f.accounting = accounting{forked: time.Now()}
+	f.report = nil
+	if !f.resuming {
+		f.setCheckpoint(nil)
+	}
+	defer func() {
+		closeFiles(f.closeAfterStart)
+		f.closeAfterStart = nil
+	}()
+	if err = spawns.take(f.WaitForSpawn); err != nil {
+		return
+	}
+	if f.Command.Path, err = f.executablePath(); err != nil {
+		return &kindError{err: err, kind: ErrChildStart}
+	}
 	f.Command.Stderr = f.Stderr
 	f.Command.Stdout = f.Stdout
 	f.Command.Stdin = f.Stdin
-	f.Command.SysProcAttr = f.SysProcAttr
+	if f.StdoutStream != nil {
+		f.Command.Stdout = addWriter(f.Command.Stdout, f.StdoutStream)
+	}
+	if f.StderrStream != nil {
+		f.Command.Stderr = addWriter(f.Command.Stderr, f.StderrStream)
+	}
+	// the files passed to the last child were closed once it started
+	f.Command.ExtraFiles = nil
+	f.Command.SysProcAttr = f.sysProcAttr()
+	if f.ProcessGroup {
+		f.setProcessGroup()
+	}
+	if f.Title != "" {
+		f.Command.Args = append([]string{f.Title}, f.Command.Args[1:]...)
+	}
 	f.Command.Env = os.Environ()
-	f.Command.Env = append(f.Command.Env, nameVar+"="+f.Name)
+	for _, kv := range f.Env {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			f.Command.Env = setEnv(f.Command.Env, kv[:i], kv[i+1:])
+		}
+	}
+	if err = f.startRecording(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.stopRecording(err)
+		}
+	}()
+	if err = f.startLogs(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			closeLogs(f.logs)
+		}
+	}()
+	f.crash = nil
+	if f.CaptureCrash {
+		f.crash = &tailBuffer{max: crashTail}
+		f.Command.Stderr = addWriter(f.Command.Stderr, f.crash)
+	}
+	if f.SocketActivation {
+		f.passListenFiles()
+	}
+	f.Command.Env = f.runtimeEnv(f.Command.Env)
+	if err = f.checkWatchdogs(); err != nil {
+		return
+	}
+	sw, err := f.openStatus()
+	if err != nil {
+		return
+	}
+	if sw != nil {
+		// the child has its own copy once started, and we mustn't hold it open
+		defer sw.Close()
+	}
+	started := false
+	defer func() {
+		if !started && f.status != nil {
+			f.status.r.Close()
+			f.status = nil
+		}
+	}()
+	if f.Foreground {
+		if err = f.takeTerminal(); err != nil {
+			return
+		}
+		defer func() {
+			if !started {
+				f.restoreTerminal()
+			}
+		}()
+	}
+	if f.ControlChannel {
+		cc, err := f.openControl()
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		defer func() {
+			if !started {
+				f.closeControl()
+			}
+		}()
+	}
+	f.Command.Env = append(f.Command.Env, Protocol{Version: ProtocolVersion}.Env(f.Name, "")...)
+	if f.Command.Env, err = f.labelEnv(f.Command.Env); err != nil {
+		return
+	}
+	if f.InitShim {
+		f.Command.Env = append(f.Command.Env, initVar+"=1")
+	}
 	af, err := ioutil.TempFile("", "gofork_*")
-	f.Command.Env = append(f.Command.Env, argsVar+"="+af.Name())
 	if err != nil {
 		return
 	}
-	enc := gob.NewEncoder(af)
-	for _, iv := range args {
-		enc.EncodeValue(reflect.ValueOf(iv))
+	f.Command.Env = append(f.Command.Env, argsVar+"="+af.Name())
+	cw := &countingWriter{w: af, limit: f.MaxArgBytes}
+	h := sha256.New()
+	ws := []io.Writer{cw, h}
+	if f.recording != nil {
+		ws = append(ws, f.recording.args)
+	}
+	w := io.MultiWriter(ws...)
+	if payload != nil {
+		_, err = io.Copy(w, payload)
+	} else if f.EncryptArgs {
+		err = f.encryptArgs(w, encode)
+	} else if redacted != nil {
+		// only the child gets the secrets, encode runs first for it to pass connections
+		if err = encode(gob.NewEncoder(cw)); err == nil {
+			err = redacted(gob.NewEncoder(io.MultiWriter(ws[1:]...)))
+		}
+	} else {
+		err = encode(gob.NewEncoder(w))
 	}
 	af.Close()
-	if err = f.Command.Start(); err != nil {
+	f.argBytes = cw.n
+	if err != nil {
+		os.Remove(af.Name())
 		return
 	}
+	f.argDigest = hex.EncodeToString(h.Sum(nil))
+	if f.VerifyExecutable {
+		if err = f.verifyExecutable(); err != nil {
+			os.Remove(af.Name())
+			return
+		}
+	}
+	f.takeFingerprint()
+	f.started = time.Now()
+	if err = f.Command.Start(); err != nil {
+		os.Remove(af.Name())
+		return &kindError{err: err, kind: ErrChildStart}
+	}
+	started = true
+	defer func() {
+		if err != nil {
+			f.abort()
+		}
+	}()
+	f.running = time.Now()
+	if f.status != nil {
+		f.status.bus, f.status.control, f.status.checkpoint = f.bus, f.control, f.setCheckpoint
+		go f.status.read()
+	}
 	f.Process = f.Command.Process
+	f.startWatchdogs()
+	f.watchExit()
+	f.watchTerminal()
+	if f.Executable != "" && sw != nil {
+		// our copy of the child's end would keep the pipe open, should the child exit
+		sw.Close()
+		if err = f.awaitAck(); err != nil {
+			return &kindError{err: err, kind: ErrChildStart}
+		}
+	}
+	if f.Network != nil && f.Network.Veth != nil {
+		if err = f.setupVeth(); err != nil {
+			return
+		}
+	}
+	if f.KillOnParentExit || f.ProcessGroup {
+		if err = f.assignJob(); err != nil {
+			return
+		}
+	}
+	if f.control != nil {
+		f.trackRunning()
+	}
+	atomic.StoreInt32(&f.state, stateForked)
 	return
 }
 
-// Wait provides a wrapper around exec.Cmd.Wait()
-func (f *Function) Wait() (err error) {
-	if err = f.Command.Wait(); err != nil {
-		return
+// abort kills a child that started, but couldn't be set up, and tears down what Wait would have
+func (f *Function) abort() {
+	f.Process.Kill()
+	f.waitReaped()
+	f.Command.Wait()
+	f.releaseJob()
+	f.stopWatchExit()
+	f.stopWatchdogs()
+	f.restoreTerminal()
+	f.closeControl()
+	if s := f.status; s != nil {
+		// our copy of the child's end may still be open
+		s.r.Close()
+		<-s.done
+		f.status = nil
 	}
-	f.ProcessState = f.Command.ProcessState
-	return
 }
 
-// private
+// passFile arranges for file to be inherited by the child, and returns the descriptor number it will have there
+func (f *Function) passFile(file *os.File) int {
+	f.Command.ExtraFiles = append(f.Command.ExtraFiles, file)
+	return 2 + len(f.Command.ExtraFiles)
+}
+
+// setEnv sets key=value in env, replacing any existing definition of key
+func setEnv(env []string, key, value string) []string {
+	return append(unsetEnv(env, key), key+"="+value)
+}
+
+// unsetEnv removes any definition of key from env
+func unsetEnv(env []string, key string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
 
 func (f *Function) validateArgs(args ...interface{}) (err error) {
 	t := f.fn.Type()
 	if len(args) != t.NumIn() {
-		return fmt.Errorf("incorrect number of args for: %s", t.String())
+		return fmt.Errorf("%w for: %s", ErrBadArgCount, t.String())
 	}
 	for i := 0; i < t.NumIn(); i++ {
-		if t.In(i).Kind() != reflect.TypeOf(args[i]).Kind() {
-			return fmt.Errorf("argument mismatch (1) %s != %s", reflect.TypeOf(args[i]).Kind(), t.In(i).Kind())
+		in := t.In(i)
+		if args[i] == nil {
+			if !nilable(in) {
+				return fmt.Errorf("%w (1) nil != %s", ErrArgType, in.Kind())
+			}
+			continue
+		}
+		at := reflect.TypeOf(args[i])
+		if in.Kind() == reflect.Interface {
+			if !at.Implements(in) {
+				return fmt.Errorf("%w (1) %s does not implement %s", ErrArgType, at, in)
+			}
+			continue
+		}
+		if in.Kind() != at.Kind() {
+			return fmt.Errorf("%w (1) %s != %s", ErrArgType, at.Kind(), in.Kind())
 		}
 	}
 	return
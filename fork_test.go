@@ -0,0 +1,41 @@
+package fork
+
+import (
+	"os"
+	"testing"
+)
+
+func benchChild(b []byte) {}
+
+func init() {
+	RegisterFunc("benchChild", benchChild)
+}
+
+// the test binary is also the children's
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func BenchmarkFork(b *testing.B) {
+	payload := make([]byte, 16<<20)
+	for _, bm := range []struct {
+		name    string
+		encrypt bool
+	}{{"plain", false}, {"encrypted", true}} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				f := NewFork("benchChild", benchChild)
+				f.EncryptArgs = bm.encrypt
+				if err := f.Fork(payload); err != nil {
+					b.Fatal(err)
+				}
+				if err := f.Wait(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Package forkui renders the output of concurrently running forks for command line tools.
+//
+// Each child's output is prefixed with its label, in its own color, one whole line at a time.
+// On a terminal, a spinner line shows which children are still running, and Summary prints a table of how they exited.
+//
+//	ui := forkui.New(os.Stderr)
+//	for i, f := range fs {
+//		ui.Attach(f, fmt.Sprintf("worker %d", i))
+//		ui.Fork(f, i)
+//	}
+//	for _, f := range fs {
+//		ui.Wait(f)
+//	}
+//	ui.Close()
+//	ui.Summary()
+package forkui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	fork "github.com/neruyzo/go-fork"
+)
+
+var (
+	colors  = []string{"36", "33", "35", "32", "34", "31", "96", "93", "95", "92", "94", "91"}
+	spinner = []string{"|", "/", "-", "\\"}
+)
+
+// UI interleaves the output of attached forks on one writer
+type UI struct {
+	w        io.Writer
+	color    bool
+	animate  bool
+	mu       sync.Mutex
+	tasks    []*task
+	byFork   map[*fork.Function]*task
+	frame    int
+	drawn    bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	// closed once the spinner has stopped
+	spun chan struct{}
+}
+
+type task struct {
+	label     string
+	color     string
+	running   bool
+	started   time.Time
+	elapsed   time.Duration
+	err       error
+	out, errw *lineWriter
+}
+
+// New creates a UI writing to w.
+// Colors and the spinner are only used if w is a terminal, and colors not if NO_COLOR is set.
+func New(w io.Writer) *UI {
+	tty := isTerminal(w)
+	u := &UI{
+		w:       w,
+		color:   tty && os.Getenv("NO_COLOR") == "",
+		animate: tty,
+		byFork:  make(map[*fork.Function]*task),
+		stop:    make(chan struct{}),
+		spun:    make(chan struct{}),
+	}
+	if u.animate {
+		go u.spin()
+	}
+	return u
+}
+
+// Attach streams the stdout and stderr of f to the UI instead, with their lines prefixed with label.
+// It must be called before f is forked, with Fork for Summary to time f from when it's forked.
+func (u *UI) Attach(f *fork.Function, label string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t := &task{
+		label:   label,
+		color:   colors[len(u.tasks)%len(colors)],
+		running: true,
+	}
+	t.out = &lineWriter{u: u, t: t}
+	t.errw = &lineWriter{u: u, t: t}
+	u.tasks = append(u.tasks, t)
+	u.byFork[f] = t
+	f.Stdout, f.StdoutStream = nil, t.out
+	f.Stderr, f.StderrStream = nil, t.errw
+}
+
+// Fork forks f, which must have been attached, with args, timing it from then for Summary
+func (u *UI) Fork(f *fork.Function, args ...interface{}) error {
+	u.mu.Lock()
+	if t, ok := u.byFork[f]; ok {
+		t.started = time.Now()
+	}
+	u.mu.Unlock()
+	return f.Fork(args...)
+}
+
+// Wait waits for f, which must have been attached, and records how it exited for Summary
+func (u *UI) Wait(f *fork.Function) error {
+	err := f.Wait()
+	u.Done(f, err)
+	return err
+}
+
+// Done records that f, which must have been attached, finished with err.
+// It is for callers that wait for f themselves.
+func (u *UI) Done(f *fork.Function, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	t, ok := u.byFork[f]
+	if !ok {
+		return
+	}
+	t.flush()
+	t.running = false
+	// the child's own run time, if it got to start, else the time since the UI forked it
+	if r := f.Report(); r != nil {
+		t.elapsed = r.WallTime
+	} else if !t.started.IsZero() {
+		t.elapsed = time.Since(t.started)
+	}
+	t.err = err
+	u.redraw()
+}
+
+// Close writes out any partial lines and stops the spinner, waiting for it to be done drawing
+func (u *UI) Close() {
+	u.stopOnce.Do(func() { close(u.stop) })
+	if u.animate {
+		<-u.spun
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, t := range u.tasks {
+		t.flush()
+	}
+	u.clear()
+}
+
+// Summary writes a table of the attached forks and how they exited
+func (u *UI) Summary() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.clear()
+	type row struct {
+		t       *task
+		status  string
+		elapsed time.Duration
+	}
+	// pad the columns ourselves, tabwriter would count the color codes
+	rows := []row{}
+	width, swidth := len("FORK"), len("STATUS")
+	for _, t := range u.tasks {
+		r := row{t, "ok", t.elapsed}
+		switch {
+		case t.running:
+			r.status = "running"
+			if !t.started.IsZero() {
+				r.elapsed = time.Since(t.started)
+			}
+		case t.err != nil:
+			r.status = t.err.Error()
+		}
+		if len(t.label) > width {
+			width = len(t.label)
+		}
+		if len(r.status) > swidth {
+			swidth = len(r.status)
+		}
+		rows = append(rows, r)
+	}
+	fmt.Fprintf(u.w, "%-*s  %-*s  %s\n", width, "FORK", swidth, "STATUS", "TIME")
+	for _, r := range rows {
+		fmt.Fprintf(u.w, "%s  %-*s  %s\n", u.paint(r.t, fmt.Sprintf("%-*s", width, r.t.label)), swidth, r.status, r.elapsed.Round(time.Millisecond))
+	}
+}
+
+func (u *UI) paint(t *task, s string) string {
+	if !u.color {
+		return s
+	}
+	return "\x1b[" + t.color + "m" + s + "\x1b[0m"
+}
+
+// line writes one line of output from t; u.mu is held
+func (u *UI) line(t *task, p []byte) {
+	u.clear()
+	fmt.Fprintf(u.w, "%s %s\n", u.paint(t, "["+t.label+"]"), bytes.TrimRight(p, "\r\n"))
+	u.redraw()
+}
+
+// clear erases the spinner line; u.mu is held
+func (u *UI) clear() {
+	if u.drawn {
+		fmt.Fprint(u.w, "\r\x1b[K")
+		u.drawn = false
+	}
+}
+
+// redraw draws the spinner line; u.mu is held
+func (u *UI) redraw() {
+	if !u.animate {
+		return
+	}
+	u.clear()
+	var running []string
+	for _, t := range u.tasks {
+		if t.running {
+			running = append(running, u.paint(t, t.label))
+		}
+	}
+	if len(running) == 0 {
+		return
+	}
+	fmt.Fprintf(u.w, "%s %d running:", spinner[u.frame%len(spinner)], len(running))
+	for _, r := range running {
+		fmt.Fprint(u.w, " ", r)
+	}
+	u.drawn = true
+}
+
+func (u *UI) spin() {
+	defer close(u.spun)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-u.stop:
+			return
+		case <-tick.C:
+			u.mu.Lock()
+			u.frame++
+			// drawn or not, as children may run a while before their first line of output
+			u.redraw()
+			u.mu.Unlock()
+		}
+	}
+}
+
+// lineWriter hands whole lines written to it to the UI
+type lineWriter struct {
+	u   *UI
+	t   *task
+	buf []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.u.mu.Lock()
+	defer l.u.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.u.line(l.t, l.buf[:i+1])
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes out a partial line; u.mu is held
+func (t *task) flush() {
+	for _, l := range []*lineWriter{t.out, t.errw} {
+		if len(l.buf) > 0 {
+			l.u.line(t, l.buf)
+			l.buf = nil
+		}
+	}
+}
+
+// isTerminal reports whether w is a character device, as terminals are
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,91 @@
+package forkui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	fork "github.com/neruyzo/go-fork"
+)
+
+// newTestUI returns a UI writing to a buffer, which is only to be read with u.mu held
+func newTestUI(animate bool) (*UI, *bytes.Buffer) {
+	var buf bytes.Buffer
+	u := New(&buf)
+	u.animate = animate
+	if animate {
+		go u.spin()
+	}
+	return u, &buf
+}
+
+func attach(u *UI, label string) *fork.Function {
+	f := fork.NewFork(label, func() {})
+	u.Attach(f, label)
+	return f
+}
+
+func TestLineWriterSplits(t *testing.T) {
+	u, buf := newTestUI(false)
+	f := attach(u, "w")
+	for _, s := range []string{"a\nb", "c\n", "d\ne\n", "", "f\r\n"} {
+		if n, err := f.StdoutStream.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	want := "[w] a\n[w] bc\n[w] d\n[w] e\n[w] f\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDoneFlushes(t *testing.T) {
+	u, buf := newTestUI(false)
+	f := attach(u, "w")
+	f.StdoutStream.Write([]byte("out"))
+	f.StderrStream.Write([]byte("err"))
+	if buf.Len() != 0 {
+		t.Fatalf("partial lines written before Done: %q", buf.String())
+	}
+	u.Done(f, errors.New("failed"))
+	want := "[w] out\n[w] err\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	buf.Reset()
+	u.Summary()
+	if got := buf.String(); !strings.Contains(got, "failed") {
+		t.Errorf("summary doesn't have the error: %q", got)
+	}
+}
+
+func TestSpinnerBeforeOutput(t *testing.T) {
+	u, buf := newTestUI(true)
+	defer u.Close()
+	attach(u, "w")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		u.mu.Lock()
+		got := buf.String()
+		u.mu.Unlock()
+		if strings.Contains(got, "1 running: w") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("no spinner line before the first line of output")
+}
+
+func TestCloseStopsSpinner(t *testing.T) {
+	u, buf := newTestUI(true)
+	attach(u, "w")
+	u.Close()
+	// nothing may be drawn once Close returns, without its lock to wait for
+	n := buf.Len()
+	time.Sleep(250 * time.Millisecond)
+	if buf.Len() != n {
+		t.Errorf("spinner drew after Close: %q", buf.String()[n:])
+	}
+}
@@ -0,0 +1,11 @@
+//go:build gofuzz
+// +build gofuzz
+
+package fork
+
+// Fuzz is a go-fuzz (github.com/dvyukov/go-fuzz) entry point.
+// data is decoded as the arguments of each of fuzzFuncs, and anything that decodes must survive a round trip unchanged.
+// Signatures with connections are skipped: their references name descriptors, which a child would take over.
+func Fuzz(data []byte) int {
+	return fuzzArgs(data)
+}
@@ -0,0 +1,91 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// seeds are the args of the seed corpus, one for each of fuzzFuncs
+func seeds() [][]interface{} {
+	n, s := 42, "s"
+	ps := &s
+	return [][]interface{}{
+		{-1, "hello", []byte{0, 1, 2}},
+		{map[string]int{"a": 1}, []string{"x", ""}, map[int]map[string]bool{1: {"t": true}}},
+		{&n, &ps, &[]*int{&n}},
+		{map[string]interface{}{"k": []interface{}{1, "v"}}, nil},
+		{fuzzStruct{A: 1, B: &s, C: map[string][]*int{"c": {&n}}, D: 2, E: []fuzzStruct{{A: 3}}}, &fuzzStruct{A: 4}},
+	}
+}
+
+// fuzz calls the harness of fuzz.go on data, failing the test where it would crash the fuzzer
+func fuzz(t *testing.T, data []byte) (found int) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%x: %v", data, r)
+		}
+	}()
+	return fuzzArgs(data)
+}
+
+func TestFuzzSeeds(t *testing.T) {
+	registerFuzzTypes()
+	for k, seed := range seeds() {
+		fn := fuzzFuncs[k]
+		data, err := EncodeArgs(fn, seed...)
+		if err != nil {
+			t.Fatalf("%T: %v", fn, err)
+		}
+		if fuzz(t, data) == 0 {
+			t.Fatalf("%T: seed doesn't decode", fn)
+		}
+		args, err := DecodeArgs(fn, data)
+		if err != nil {
+			t.Fatalf("%T: %v", fn, err)
+		}
+		for i := range args {
+			if !reflect.DeepEqual(args[i], seed[i]) {
+				t.Errorf("%T: arg %d: got %s, want %s", fn, i+1, describe(args[i]), describe(seed[i]))
+			}
+		}
+		// the first steps a fuzzer would take: cut the seed short, and flip its bits
+		for n := 0; n < len(data); n++ {
+			fuzz(t, data[:n])
+		}
+		for i := range data {
+			mutated := append([]byte(nil), data...)
+			mutated[i] ^= 0xff
+			fuzz(t, mutated)
+		}
+	}
+}
+
+func TestDecodeArgsConn(t *testing.T) {
+	// a reference to stdin, which DecodeArgs must not take over and close
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(connRef{Fd: int(os.Stdin.Fd())}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeArgs(func(net.Conn) {}, buf.Bytes()); err == nil {
+		t.Fatal("decoded a connection")
+	}
+	if _, err := os.Stdin.Stat(); err != nil {
+		t.Fatalf("stdin: %v", err)
+	}
+	args, err := RoundTrip(func(net.Conn) {}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != nil {
+		t.Errorf("got %s for a nil connection", describe(args[0]))
+	}
+}
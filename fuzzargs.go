@@ -0,0 +1,72 @@
+package fork
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type fuzzStruct struct {
+	A int
+	B *string
+	C map[string][]*int
+	D interface{}
+	E []fuzzStruct
+}
+
+// fuzzFuncs are signatures exercising the corners of gob: maps, nested pointers and interface values
+var fuzzFuncs = []interface{}{
+	func(int, string, []byte) {},
+	func(map[string]int, []string, map[int]map[string]bool) {},
+	func(*int, **string, *[]*int) {},
+	func(interface{}, fmt.Stringer) {},
+	func(fuzzStruct, *fuzzStruct) {},
+}
+
+// registered on first use, not to add to the gob names of programs that never fuzz
+var fuzzRegister sync.Once
+
+func registerFuzzTypes() {
+	fuzzRegister.Do(func() {
+		gob.Register(fuzzStruct{})
+		gob.Register(map[string]interface{}{})
+		gob.Register([]interface{}{})
+	})
+}
+
+// fuzzArgs decodes data as the arguments of each of fuzzFuncs, and panics if anything that decodes doesn't survive
+// a round trip unchanged. It returns 1 if data decoded for any of them, for Fuzz.
+func fuzzArgs(data []byte) int {
+	registerFuzzTypes()
+	found := 0
+	for _, fn := range fuzzFuncs {
+		if hasConns(fn) {
+			continue
+		}
+		args, err := DecodeArgs(fn, data)
+		if err != nil {
+			continue
+		}
+		found = 1
+		again, err := RoundTrip(fn, args...)
+		if err != nil {
+			panic(fmt.Sprintf("%T: round trip of decoded args failed: %v", fn, err))
+		}
+		if !reflect.DeepEqual(args, again) {
+			panic(fmt.Sprintf("%T: round trip changed args: %#v != %#v", fn, args, again))
+		}
+	}
+	return found
+}
+
+// hasConns reports whether the func fn takes a connection
+func hasConns(fn interface{}) bool {
+	t := reflect.TypeOf(fn)
+	for i := 0; i < t.NumIn(); i++ {
+		if connTypes[t.In(i)] {
+			return true
+		}
+	}
+	return false
+}
@@ -1,3 +1,3 @@
 module github.com/neruyzo/go-fork
 
-go 1.15
+go 1.16
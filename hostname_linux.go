@@ -0,0 +1,14 @@
+package fork
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// sethostname sets the hostname of our (new) UTS namespace
+func sethostname(name string) error {
+	if err := syscall.Sethostname([]byte(name)); err != nil {
+		return fmt.Errorf("sethostname %q: %v", name, err)
+	}
+	return nil
+}
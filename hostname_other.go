@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+func sethostname(name string) error {
+	return errors.New("setting the hostname is only supported on Linux")
+}
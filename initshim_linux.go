@@ -0,0 +1,110 @@
+package fork
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// initShim makes us a minimal init when we are PID 1 of a new PID namespace.
+// The fork runs as our child, inheriting our environment and descriptors,
+// while we forward it signals and reap everything that gets reparented to us.
+// We exit with the fork's status; if we aren't PID 1 this returns and the fork runs here.
+// A fork killed by a signal can't be mirrored by raising it, as PID 1 ignores the signals it sends itself,
+// so we exit with 128 plus its number, as shells do, and the parent decodes that (see exitSignal).
+func initShim() {
+	os.Unsetenv(initVar)
+	if os.Getpid() != 1 {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		panic("init shim: " + err.Error())
+	}
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs)
+	cmd := exec.Command(exe)
+	cmd.Args = os.Args
+	if pid := outerPid(); pid > 0 {
+		cmd.Env = append(os.Environ(), hostPidVar+"="+strconv.Itoa(pid))
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = inheritedFiles()
+	if err := cmd.Start(); err != nil {
+		panic("init shim: " + err.Error())
+	}
+	pid := cmd.Process.Pid
+	go func() {
+		for s := range sigs {
+			switch s {
+			case syscall.SIGCHLD, syscall.SIGURG:
+				// SIGCHLD is ours, SIGURG is used internally by the Go runtime
+			default:
+				syscall.Kill(pid, s.(syscall.Signal))
+			}
+		}
+	}()
+	for {
+		var ws syscall.WaitStatus
+		wpid, err := syscall.Wait4(-1, &ws, 0, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			// nothing left to reap
+			os.Exit(1)
+		}
+		if wpid != pid {
+			continue
+		}
+		if ws.Signaled() {
+			os.Exit(128 + int(ws.Signal()))
+		}
+		os.Exit(ws.ExitStatus())
+	}
+}
+
+// outerPid returns our pid in the PID namespace of /proc, which, unless the fork remounts it, is our parent's:
+// the first of the NSpid line of our status. It returns 0 if that can't be told (before Linux 4.1).
+func outerPid() int {
+	b, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// NSpid:	12345	1
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "NSpid:" {
+			pid, _ := strconv.Atoi(fields[1])
+			return pid
+		}
+	}
+	return 0
+}
+
+// inheritedFiles returns the descriptors above stderr that we inherited (i.e. that aren't close-on-exec),
+// indexed for exec.Cmd.ExtraFiles so they keep their numbers in the child.
+func inheritedFiles() (files []*os.File) {
+	ents, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return
+	}
+	for _, e := range ents {
+		fd, err := strconv.Atoi(e.Name())
+		if err != nil || fd < 3 {
+			continue
+		}
+		flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFD, 0)
+		if errno != 0 || flags&syscall.FD_CLOEXEC != 0 {
+			continue
+		}
+		for len(files) < fd-2 {
+			files = append(files, nil)
+		}
+		files[fd-3] = os.NewFile(uintptr(fd), e.Name())
+	}
+	return
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "os"
+
+// initShim is a no-op; PID namespaces only exist on Linux.
+func initShim() {
+	os.Unsetenv(initVar)
+}
@@ -0,0 +1,86 @@
+package fork
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ExecutableChangedError is returned by Fork when VerifyExecutable is set,
+// and the executable on disk no longer matches the one we recorded.
+type ExecutableChangedError struct {
+	Path string
+	// Want and Got are the hex SHA-256 digests recorded, and found now
+	Want string
+	Got  string
+}
+
+func (e *ExecutableChangedError) Error() string {
+	return fmt.Sprintf("executable %s has changed: sha256 %s, expected %s", e.Path, e.Got, e.Want)
+}
+
+// the digests of executables we've recorded, by path
+var executables struct {
+	sync.Mutex
+	digests map[string]string
+}
+
+// recordExecutable records the digest of the executable at path, unless we already have
+func recordExecutable(path string) (digest string, err error) {
+	executables.Lock()
+	defer executables.Unlock()
+	if d, ok := executables.digests[path]; ok {
+		return d, nil
+	}
+	if digest, err = fileDigest(path); err != nil {
+		return
+	}
+	if executables.digests == nil {
+		executables.digests = make(map[string]string)
+	}
+	executables.digests[path] = digest
+	return
+}
+
+// recordedExecutable returns the digest recorded for the executable at path, if there is one
+func recordedExecutable(path string) (string, bool) {
+	executables.Lock()
+	defer executables.Unlock()
+	d, ok := executables.digests[path]
+	return d, ok
+}
+
+// verifyExecutable checks that the executable we're about to start matches the digest recorded for it.
+// If nothing was recorded (f was never registered), the first fork records it.
+func (f *Function) verifyExecutable() error {
+	path := f.Command.Path
+	want, err := recordExecutable(path)
+	if err != nil {
+		return err
+	}
+	got, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return &ExecutableChangedError{Path: path, Want: want, Got: got}
+	}
+	return nil
+}
+
+// fileDigest returns the hex SHA-256 of the file at path
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,63 @@
+package fork
+
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// jailAttach moves the process into jail, given by jid or name, see jail_attach(2)
+func jailAttach(jail string) error {
+	jid, err := jailID(jail)
+	if err != nil {
+		return err
+	}
+	if _, _, e := syscall.Syscall(syscall.SYS_JAIL_ATTACH, uintptr(jid), 0, 0); e != 0 {
+		return fmt.Errorf("jail_attach %q: %v", jail, e)
+	}
+	return nil
+}
+
+// jailID resolves a jail name to its jid, like jail_getid(3)
+func jailID(jail string) (int, error) {
+	if jid, err := strconv.Atoi(jail); err == nil {
+		return jid, nil
+	}
+	iov := make([]syscall.Iovec, 4)
+	param := func(i int, b []byte) {
+		iov[i].Base = &b[0]
+		iov[i].SetLen(len(b))
+	}
+	errmsg := make([]byte, 256)
+	param(0, []byte("name\x00"))
+	param(1, []byte(jail+"\x00"))
+	param(2, []byte("errmsg\x00"))
+	param(3, errmsg)
+	jid, _, e := syscall.Syscall(syscall.SYS_JAIL_GET, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)), 0)
+	if e != 0 {
+		if n := clen(errmsg); n > 0 {
+			return -1, fmt.Errorf("jail %q: %s", jail, errmsg[:n])
+		}
+		return -1, fmt.Errorf("jail %q: %v", jail, e)
+	}
+	return int(jid), nil
+}
+
+// capEnter puts the process in Capsicum capability mode, see cap_enter(2)
+func capEnter() error {
+	if _, _, e := syscall.Syscall(syscall.SYS_CAP_ENTER, 0, 0, 0); e != 0 {
+		return fmt.Errorf("cap_enter: %v", e)
+	}
+	return nil
+}
+
+// clen returns the length of the NUL terminated string in b
+func clen(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return len(b)
+}
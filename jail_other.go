@@ -0,0 +1,14 @@
+//go:build !freebsd
+// +build !freebsd
+
+package fork
+
+import "errors"
+
+func jailAttach(jail string) error {
+	return errors.New("jails are only supported on FreeBSD")
+}
+
+func capEnter() error {
+	return errors.New("capsicum is only supported on FreeBSD")
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package fork
+
+// assignJob is a no-op; Job Objects only exist on Windows.
+func (f *Function) assignJob() (err error) {
+	return
+}
+
+// releaseJob is a no-op; Job Objects only exist on Windows.
+func (f *Function) releaseJob() {}
@@ -0,0 +1,111 @@
+package fork
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitKillOnJobClose           = 0x2000
+
+	processSetQuota  = 0x0100
+	processTerminate = 0x0001
+)
+
+// JOBOBJECT_BASIC_LIMIT_INFORMATION
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+// IO_COUNTERS
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+// JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// assignJob creates a Job Object and places the child in it, for KillTree.
+// With KillOnParentExit, the job is kill-on-close, and the job handle is held until the child is waited for
+// (or until we exit), so the child can't outlive us.
+//
+// The child runs briefly before it is assigned, so anything it spawns in that window escapes the job.
+func (f *Function) assignJob() (err error) {
+	job, _, e := procCreateJobObjectW.Call(0, 0)
+	if job == 0 {
+		return fmt.Errorf("CreateJobObject: %v", e)
+	}
+	info := jobObjectExtendedLimitInformation{}
+	if f.KillOnParentExit {
+		info.BasicLimitInformation.LimitFlags = jobObjectLimitKillOnJobClose
+	}
+	if r, _, e := procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformationClass, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info)); r == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("SetInformationJobObject: %v", e)
+	}
+	p, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(f.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("OpenProcess: %v", err)
+	}
+	defer syscall.CloseHandle(p)
+	if r, _, e := procAssignProcessToJobObject.Call(job, uintptr(p)); r == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return fmt.Errorf("AssignProcessToJobObject: %v", e)
+	}
+	f.job = job
+	return
+}
+
+// releaseJob closes the job handle of the child, once it has exited.
+// With KillOnParentExit, this ends what the child left running in its job.
+func (f *Function) releaseJob() {
+	if f.job != 0 {
+		syscall.CloseHandle(syscall.Handle(f.job))
+		f.job = 0
+	}
+}
+
+// killTree terminates the job of the child, and so its descendants, or only the child if it has no job
+func (f *Function) killTree() error {
+	if f.job == 0 {
+		return f.Process.Kill()
+	}
+	if r, _, e := procTerminateJobObject.Call(f.job, 1); r == 0 {
+		return fmt.Errorf("TerminateJobObject: %v", e)
+	}
+	return nil
+}
+
+// setProcessGroup is a no-op; on Windows, the child's tree is its job
+func (f *Function) setProcessGroup() {}
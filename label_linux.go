@@ -0,0 +1,65 @@
+package fork
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+const (
+	selinuxVar  = "GOFORK_SELINUX"
+	apparmorVar = "GOFORK_APPARMOR"
+)
+
+// labelEnv adds the security labels for the child to env
+func (f *Function) labelEnv(env []string) ([]string, error) {
+	if f.SELinuxLabel != "" && f.AppArmorProfile != "" {
+		return nil, fmt.Errorf("can't use both an SELinux label and an AppArmor profile")
+	}
+	if f.SELinuxLabel != "" {
+		env = append(env, selinuxVar+"="+f.SELinuxLabel)
+	}
+	if f.AppArmorProfile != "" {
+		env = append(env, apparmorVar+"="+f.AppArmorProfile)
+	}
+	return env, nil
+}
+
+// initLabel re-executes us under the security label our parent asked for, if any.
+// Labels only change on exec, so this is done before anything else in the child; it doesn't return, unless it fails.
+func initLabel() {
+	attr, label := "", ""
+	if label = os.Getenv(selinuxVar); label != "" {
+		attr = "/proc/thread-self/attr/exec"
+	} else if label = os.Getenv(apparmorVar); label != "" {
+		attr = "/proc/thread-self/attr/apparmor/exec"
+		if _, err := os.Stat(attr); err != nil {
+			// kernels before 5.8 only have the shared interface
+			attr = "/proc/thread-self/attr/exec"
+		}
+		label = "exec " + label
+	} else {
+		return
+	}
+	os.Unsetenv(selinuxVar)
+	os.Unsetenv(apparmorVar)
+	exe, err := os.Executable()
+	if err != nil {
+		labelFailed(err)
+	}
+	// the exec attribute belongs to the thread, which must be the one calling exec
+	runtime.LockOSThread()
+	if err := ioutil.WriteFile(attr, []byte(label), 0); err != nil {
+		labelFailed(err)
+	}
+	err = syscall.Exec(exe, os.Args, os.Environ())
+	labelFailed(err)
+}
+
+// labelFailed reports that we couldn't change label, which is early enough that we still have to set up the status pipe
+func labelFailed(err error) {
+	initStatus()
+	fail("security label: "+err.Error(), "fork failed: security label: "+err.Error())
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+func (f *Function) labelEnv(env []string) ([]string, error) {
+	if f.SELinuxLabel != "" || f.AppArmorProfile != "" {
+		return nil, errors.New("security labels are only supported on Linux")
+	}
+	return env, nil
+}
+
+func initLabel() {}
@@ -0,0 +1,116 @@
+package fork
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// see linux/landlock.h
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	landlockAccessFSExecute    = 1 << 0
+	landlockAccessFSWriteFile  = 1 << 1
+	landlockAccessFSReadFile   = 1 << 2
+	landlockAccessFSReadDir    = 1 << 3
+	landlockAccessFSRemoveDir  = 1 << 4
+	landlockAccessFSRemoveFile = 1 << 5
+	landlockAccessFSMakeChar   = 1 << 6
+	landlockAccessFSMakeDir    = 1 << 7
+	landlockAccessFSMakeReg    = 1 << 8
+	landlockAccessFSMakeSock   = 1 << 9
+	landlockAccessFSMakeFifo   = 1 << 10
+	landlockAccessFSMakeBlock  = 1 << 11
+	landlockAccessFSMakeSym    = 1 << 12
+	landlockAccessFSRefer      = 1 << 13 // ABI 2
+	landlockAccessFSTruncate   = 1 << 14 // ABI 3
+
+	landlockAccessFSRead = landlockAccessFSExecute | landlockAccessFSReadFile | landlockAccessFSReadDir
+
+	prSetNoNewPrivs = 38
+	oPath           = 0x200000
+)
+
+type landlockRulesetAttr struct {
+	HandledAccessFS uint64
+}
+
+type landlockPathBeneathAttr struct {
+	AllowedAccess uint64
+	ParentFd      int32
+}
+
+// landlock restricts the whole process to the paths in r.
+// Landlock domains are per-thread, so no_new_privs and the restriction itself are applied to all threads.
+// Kernels without landlock are tolerated unless r.Required is set.
+func landlock(r *LandlockRuleset) error {
+	abi, _, e := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if e != 0 {
+		if !r.Required && (e == syscall.ENOSYS || e == syscall.EOPNOTSUPP) {
+			return nil
+		}
+		return fmt.Errorf("landlock: %v", e)
+	}
+	handled := uint64(landlockAccessFSRead | landlockAccessFSWriteFile | landlockAccessFSRemoveDir | landlockAccessFSRemoveFile |
+		landlockAccessFSMakeChar | landlockAccessFSMakeDir | landlockAccessFSMakeReg | landlockAccessFSMakeSock |
+		landlockAccessFSMakeFifo | landlockAccessFSMakeBlock | landlockAccessFSMakeSym)
+	if abi >= 2 {
+		handled |= landlockAccessFSRefer
+	}
+	if abi >= 3 {
+		handled |= landlockAccessFSTruncate
+	}
+	attr := landlockRulesetAttr{HandledAccessFS: handled}
+	fd, _, e := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if e != 0 {
+		return fmt.Errorf("landlock: create ruleset: %v", e)
+	}
+	defer syscall.Close(int(fd))
+	for _, p := range r.ReadOnly {
+		if err := landlockAddPath(int(fd), p, landlockAccessFSRead); err != nil {
+			return err
+		}
+	}
+	for _, p := range r.ReadWrite {
+		if err := landlockAddPath(int(fd), p, handled); err != nil {
+			return err
+		}
+	}
+	if _, _, e := syscall.AllThreadsSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); e != 0 {
+		return fmt.Errorf("landlock: set no_new_privs: %v", e)
+	}
+	if _, _, e := syscall.AllThreadsSyscall(sysLandlockRestrictSelf, fd, 0, 0); e != 0 {
+		return fmt.Errorf("landlock: restrict self: %v", e)
+	}
+	return nil
+}
+
+// landlockAddPath allows access to the hierarchy beneath path
+func landlockAddPath(ruleset int, path string, access uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("landlock: %s: %v", path, err)
+	}
+	defer syscall.Close(fd)
+	var st syscall.Stat_t
+	if err = syscall.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("landlock: %s: %v", path, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		// directory-only rights can't be granted on files
+		access &^= landlockAccessFSReadDir | landlockAccessFSRemoveDir | landlockAccessFSRemoveFile |
+			landlockAccessFSMakeChar | landlockAccessFSMakeDir | landlockAccessFSMakeReg | landlockAccessFSMakeSock |
+			landlockAccessFSMakeFifo | landlockAccessFSMakeBlock | landlockAccessFSMakeSym | landlockAccessFSRefer
+	}
+	attr := landlockPathBeneathAttr{AllowedAccess: access, ParentFd: int32(fd)}
+	if _, _, e := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); e != 0 {
+		return fmt.Errorf("landlock: %s: %v", path, e)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+func landlock(r *LandlockRuleset) error {
+	return errors.New("landlock is only supported on Linux")
+}
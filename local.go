@@ -0,0 +1,92 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// set when all forks should run in process
+var inProcess int32
+
+// ForceInProcess makes Fork (and ReFork and Replay) run functions in this process, in a new goroutine, rather than in a child.
+// Arguments still go through the same encode/decode round trip, so tests and debuggers can step through worker code.
+// Child-side settings (sandboxing, limits, ...) are not applied, and a function that exits will take the whole process with it.
+func ForceInProcess(force bool) {
+	var v int32
+	if force {
+		v = 1
+	}
+	atomic.StoreInt32(&inProcess, v)
+}
+
+func forcedInProcess() bool {
+	return atomic.LoadInt32(&inProcess) != 0
+}
+
+// CallLocal calls the function in this process, and waits for it to return.
+// The arguments go through the same encode/decode round trip they would for a fork.
+// If the function panics, or its last result is a non-nil error, CallLocal returns that as an error.
+func (f *Function) CallLocal(args ...interface{}) (err error) {
+	if err = f.validateArgs(args...); err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if args, err = f.holdConns(args); err != nil {
+		return
+	}
+	if err = encodeArgs(gob.NewEncoder(&buf), f.fn.Type(), args); err != nil {
+		releaseConns(args)
+		return
+	}
+	return f.callDecoded(gob.NewDecoder(&buf))
+}
+
+// forkLocal starts the function in process, for Wait to wait for
+func (f *Function) forkLocal(args ...interface{}) (err error) {
+	var buf bytes.Buffer
+	if args, err = f.holdConns(args); err != nil {
+		return
+	}
+	if err = encodeArgs(gob.NewEncoder(&buf), f.fn.Type(), args); err != nil {
+		releaseConns(args)
+		return
+	}
+	done := make(chan error, 1)
+	f.local = done
+	exit := newExitWatch()
+	f.exit = exit
+	atomic.StoreInt32(&f.state, stateForked)
+	go func() {
+		done <- f.callDecoded(gob.NewDecoder(&buf))
+		exit.close()
+	}()
+	return
+}
+
+// callDecoded decodes arguments with dec and calls the function with them
+func (f *Function) callDecoded(dec *gob.Decoder) (err error) {
+	args, err := decodeArgs(dec, f.fn.Type(), true)
+	if err != nil {
+		return &ChildError{Message: err.Error()}
+	}
+	return call(f.fn, args)
+}
+
+// call calls fn, turning a panic or a returned error into an error
+func call(fn reflect.Value, args []reflect.Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &kindError{err: fmt.Errorf("panic: %v", r), kind: ErrChildCrash}
+		}
+	}()
+	out := fn.Call(args)
+	if n := len(out); n > 0 {
+		if e, ok := out[n-1].Interface().(error); ok && e != nil {
+			return e
+		}
+	}
+	return
+}
@@ -0,0 +1,156 @@
+package fork
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tells apart the logs of forks of the same name in the same second
+var logSeq int64
+
+// startLogs opens the log files for the stdout and stderr of the fork about to start, if we're logging,
+// first pruning those past LogRetention
+func (f *Function) startLogs() (err error) {
+	f.logs = nil
+	if f.LogDir == "" {
+		return
+	}
+	if err = os.MkdirAll(f.LogDir, 0700); err != nil {
+		return
+	}
+	prefix := strings.Replace(f.Name, string(filepath.Separator), "_", -1) + "-"
+	if f.LogRetention > 0 {
+		pruneLogs(f.LogDir, prefix, time.Now().Add(-f.LogRetention))
+	}
+	base := filepath.Join(f.LogDir, fmt.Sprintf("%s%s-%d", prefix, time.Now().Format("20060102T150405"), atomic.AddInt64(&logSeq, 1)))
+	var logs []*rotatingLog
+	for _, stream := range []string{"stdout", "stderr"} {
+		l := &rotatingLog{base: base + "." + stream, maxBytes: f.LogMaxBytes, maxAge: f.LogMaxAge}
+		if err = l.open(); err != nil {
+			closeLogs(logs)
+			return
+		}
+		logs = append(logs, l)
+	}
+	f.logs = logs
+	f.Command.Stdout = addWriter(f.Command.Stdout, logs[0])
+	f.Command.Stderr = addWriter(f.Command.Stderr, logs[1])
+	return
+}
+
+// logPaths returns the files the stdout and stderr of the last fork were logged to
+func (f *Function) logPaths() (stdout, stderr []string) {
+	if len(f.logs) != 2 {
+		return
+	}
+	return f.logs[0].files(), f.logs[1].files()
+}
+
+func closeLogs(logs []*rotatingLog) {
+	for _, l := range logs {
+		l.close()
+	}
+}
+
+// pruneLogs removes the log files in dir starting with prefix that were last written before t
+func pruneLogs(dir, prefix string, t time.Time) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && strings.HasPrefix(fi.Name(), prefix) && strings.HasSuffix(fi.Name(), ".log") && fi.ModTime().Before(t) {
+			os.Remove(filepath.Join(dir, fi.Name()))
+		}
+	}
+}
+
+// rotatingLog is a log file that is continued in a new, numbered, file once it has grown past maxBytes,
+// or been written to for maxAge: base.log, base.1.log, base.2.log, ...
+type rotatingLog struct {
+	mu       sync.Mutex
+	base     string
+	maxBytes int64
+	maxAge   time.Duration
+	file     *os.File
+	size     int64
+	opened   time.Time
+	paths    []string
+}
+
+func (l *rotatingLog) open() (err error) {
+	path := l.base + ".log"
+	if n := len(l.paths); n > 0 {
+		path = fmt.Sprintf("%s.%d.log", l.base, n)
+	}
+	if l.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600); err != nil {
+		return
+	}
+	l.paths = append(l.paths, path)
+	l.size, l.opened = 0, time.Now()
+	return
+}
+
+func (l *rotatingLog) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(p) > 0 {
+		if l.file == nil {
+			return n, os.ErrClosed
+		}
+		chunk := p
+		if l.maxBytes > 0 && l.size+int64(len(p)) > l.maxBytes {
+			// fill the file up to maxBytes, with whole lines if there are any to fit
+			chunk = p[:0]
+			if room := l.maxBytes - l.size; room > 0 {
+				chunk = p[:room]
+				if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+					chunk = chunk[:i+1]
+				} else if l.size > 0 {
+					chunk = p[:0]
+				}
+			}
+		}
+		if len(chunk) == 0 || l.maxAge > 0 && l.size > 0 && time.Since(l.opened) >= l.maxAge {
+			l.file.Close()
+			if err = l.open(); err != nil {
+				l.file = nil
+				return
+			}
+			if len(chunk) == 0 {
+				continue
+			}
+		}
+		k, err := l.file.Write(chunk)
+		l.size += int64(k)
+		n += k
+		if err != nil {
+			return n, err
+		}
+		p = p[k:]
+	}
+	return
+}
+
+func (l *rotatingLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// files returns the paths of the files written, in order
+func (l *rotatingLog) files() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.paths...)
+}
@@ -0,0 +1,70 @@
+package fork
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRotatingLogWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofork-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, test := range []struct {
+		maxBytes int64
+		writes   []string
+		files    []string
+	}{
+		{0, []string{"aaaa\nbbbb\n", "cccc\n"}, []string{"aaaa\nbbbb\ncccc\n"}},
+		{10, []string{"aaaa\nbbbb\ncc\n"}, []string{"aaaa\nbbbb\n", "cc\n"}},
+		{10, []string{"aaaa\nbb", "bb\ncc\n"}, []string{"aaaa\nbbbb\n", "cc\n"}},
+		// lines that don't fit whole go to the next file
+		{10, []string{"aaaa\n", "bbbbbbbb\n"}, []string{"aaaa\n", "bbbbbbbb\n"}},
+		{10, []string{"aa\nbbbbbbbbbb\n"}, []string{"aa\n", "bbbbbbbbbb", "\n"}},
+		// unless they don't fit in a file at all
+		{10, []string{"0123456789abcdefghijklmn"}, []string{"0123456789", "abcdefghij", "klmn"}},
+		{10, []string{"0123456789", "\n"}, []string{"0123456789", "\n"}},
+	} {
+		l := &rotatingLog{base: filepath.Join(dir, string(rune('a'+i))), maxBytes: test.maxBytes}
+		if err := l.open(); err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range test.writes {
+			if n, err := l.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("%d: Write(%q): %d, %v", i, w, n, err)
+			}
+		}
+		l.close()
+		var files []string
+		for _, path := range l.files() {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, string(b))
+		}
+		if !reflect.DeepEqual(files, test.files) {
+			t.Errorf("%d: got files %q, want %q", i, files, test.files)
+		}
+	}
+}
+
+func TestRotatingLogClosed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofork-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := &rotatingLog{base: filepath.Join(dir, "l")}
+	if err := l.open(); err != nil {
+		t.Fatal(err)
+	}
+	l.close()
+	if _, err := l.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("Write after close: got %v, want os.ErrClosed", err)
+	}
+}
@@ -0,0 +1,197 @@
+package fork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ManagementHooks lets a child take part in its management endpoint (see Function.ManagementSocket).
+// Any hook may be left nil.
+type ManagementHooks struct {
+	// Health is called by Fork.Health; a non-nil error reports the child unhealthy
+	Health func() error
+	// LogLevel is called by Fork.SetLogLevel with the requested level
+	LogLevel func(level string) error
+	// Stop is called by Fork.Stop to ask the child to finish up and return.
+	// Without it, Fork.Stop cancels the context of StopContext, if the child took it, or else removes the
+	// management socket and sends the child SIGTERM (on Windows, the child exits).
+	Stop func()
+}
+
+var (
+	managementMu    sync.Mutex
+	managementHooks ManagementHooks
+	managementPath  string
+	managementLn    net.Listener
+	managementStart time.Time
+	// the context of StopContext, once it is taken
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+)
+
+// SetManagementHooks installs the hooks the child's management endpoint calls.
+// It is called by the child, typically at the start of its function.
+func SetManagementHooks(h ManagementHooks) {
+	managementMu.Lock()
+	managementHooks = h
+	managementMu.Unlock()
+}
+
+// StopContext returns a context that is canceled when Fork.Stop is called on the child's management endpoint,
+// so the function can finish up and return, as with a Stop hook. A child that takes it isn't sent SIGTERM.
+func StopContext() context.Context {
+	managementMu.Lock()
+	defer managementMu.Unlock()
+	if stopCtx == nil {
+		stopCtx, stopCancel = context.WithCancel(context.Background())
+	}
+	return stopCtx
+}
+
+func hooks() ManagementHooks {
+	managementMu.Lock()
+	defer managementMu.Unlock()
+	return managementHooks
+}
+
+// ManagementStats is the result of Fork.Stats on a child's management endpoint
+type ManagementStats struct {
+	Pid        int           `json:"pid"`
+	Uptime     time.Duration `json:"uptime"`
+	Goroutines int           `json:"goroutines"`
+	HeapAlloc  uint64        `json:"heap_alloc"`
+	Sys        uint64        `json:"sys"`
+	NumGC      uint32        `json:"num_gc"`
+}
+
+// management is the "Fork" JSON-RPC service served on a child's management socket
+type management struct{}
+
+// Health reports "ok", or the error from the Health hook
+func (management) Health(_ struct{}, reply *string) error {
+	if h := hooks().Health; h != nil {
+		if err := h(); err != nil {
+			return err
+		}
+	}
+	*reply = "ok"
+	return nil
+}
+
+// Stats reports runtime statistics of the child
+func (management) Stats(_ struct{}, reply *ManagementStats) error {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	*reply = ManagementStats{
+		Pid:        hostPid,
+		Uptime:     time.Since(managementStart),
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  m.HeapAlloc,
+		Sys:        m.Sys,
+		NumGC:      m.NumGC,
+	}
+	return nil
+}
+
+// SetLogLevel hands level to the LogLevel hook
+func (management) SetLogLevel(level string, reply *bool) error {
+	h := hooks().LogLevel
+	if h == nil {
+		return errors.New("log level can't be changed by this child")
+	}
+	if err := h(level); err != nil {
+		return err
+	}
+	*reply = true
+	return nil
+}
+
+// Stop asks the child to stop gracefully
+func (management) Stop(_ struct{}, reply *bool) error {
+	*reply = true
+	if h := hooks().Stop; h != nil {
+		go h()
+		return nil
+	}
+	managementMu.Lock()
+	cancel := stopCancel
+	managementMu.Unlock()
+	if cancel != nil {
+		cancel()
+		return nil
+	}
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	go func() {
+		// give the reply a chance to go out first
+		time.Sleep(10 * time.Millisecond)
+		// SIGTERM likely ends us before we could
+		stopManagement()
+		if p.Signal(syscall.SIGTERM) != nil {
+			os.Exit(0)
+		}
+	}()
+	return nil
+}
+
+// serveManagement starts the management endpoint of a child, on the unix socket at path.
+// Any "%p" in path is replaced with the child's pid, as the parent knows it.
+// A socket already at path is taken over if it is stale, but not from a child still serving on it.
+func serveManagement(path string) (err error) {
+	path = strings.Replace(path, "%p", strconv.Itoa(hostPid), -1)
+	// a stale socket from an earlier child would keep us from listening
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return fmt.Errorf("management socket %s is in use, by another child: use %%p in its path to tell them apart", path)
+		}
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return
+	}
+	srv := rpc.NewServer()
+	if err = srv.RegisterName("Fork", management{}); err != nil {
+		ln.Close()
+		return
+	}
+	managementMu.Lock()
+	managementPath, managementLn, managementStart = path, ln, time.Now()
+	managementMu.Unlock()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return
+}
+
+// stopManagement closes and removes the management socket, if we have one
+func stopManagement() {
+	managementMu.Lock()
+	defer managementMu.Unlock()
+	if managementLn == nil {
+		return
+	}
+	managementLn.Close()
+	os.Remove(managementPath)
+	managementLn = nil
+}
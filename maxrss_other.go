@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package fork
+
+import "os"
+
+// maxRSS isn't known here
+func maxRSS(ps *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,68 @@
+package fork
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// MisuseMode is what happens when a Function is used out of order (see DetectMisuse)
+type MisuseMode int32
+
+const (
+	// MisuseIgnore lets misuse through to fail as it will (the default)
+	MisuseIgnore MisuseMode = iota
+	// MisuseWarn logs misuse with the log package, then carries on
+	MisuseWarn
+	// MisuseError fails the misused call with an error wrapping ErrMisuse
+	MisuseError
+)
+
+var misuseMode int32
+
+// DetectMisuse sets how Fork, ReFork and Wait react to being called out of order: forking a Function again
+// before its last child was waited for (whether or not that child is still running), or waiting without a child to wait for.
+// Without it, these fail in confusing ways, from deep in exec.Cmd, or not at all.
+func DetectMisuse(mode MisuseMode) {
+	atomic.StoreInt32(&misuseMode, int32(mode))
+}
+
+// the usage states of a Function
+const (
+	stateIdle int32 = iota
+	stateForked
+)
+
+// checkFork checks that f can be forked now
+func (f *Function) checkFork() error {
+	if atomic.LoadInt32(&f.state) != stateForked {
+		return nil
+	}
+	msg := "Fork called again before Wait"
+	if p := f.Command.Process; p != nil {
+		// an exited child stays around until waited for, so we can't tell if it's still running
+		msg = fmt.Sprintf("Fork called while the previous child (pid %d) may still be running: it wasn't waited for", p.Pid)
+	}
+	return f.misuse(msg)
+}
+
+// checkWait checks that f has a child to wait for
+func (f *Function) checkWait() error {
+	if atomic.LoadInt32(&f.state) == stateForked {
+		return nil
+	}
+	if f.Command.ProcessState != nil || f.report != nil {
+		return f.misuse("Wait called twice for the same child")
+	}
+	return f.misuse("Wait called before Fork")
+}
+
+func (f *Function) misuse(msg string) error {
+	switch MisuseMode(atomic.LoadInt32(&misuseMode)) {
+	case MisuseWarn:
+		log.Printf("go-fork: %s: %s", f.Name, msg)
+	case MisuseError:
+		return fmt.Errorf("%w: %s: %s", ErrMisuse, f.Name, msg)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fork
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// sysProcAttr returns the SysProcAttr to start the child with,
+// adding any namespaces the child config depends on to those already requested.
+func (f *Function) sysProcAttr() *syscall.SysProcAttr {
+	var flags uintptr
+	if len(f.Mounts) > 0 || f.PrivateTmp {
+		flags |= syscall.CLONE_NEWNS
+	}
+	if f.Network != nil {
+		flags |= syscall.CLONE_NEWNET
+	}
+	if f.InitShim {
+		flags |= syscall.CLONE_NEWPID
+	}
+	if f.Hostname != "" {
+		flags |= syscall.CLONE_NEWUTS
+	}
+	if flags == 0 {
+		return f.SysProcAttr
+	}
+	attr := &syscall.SysProcAttr{}
+	if f.SysProcAttr != nil {
+		// copy, so we don't modify the caller's attributes
+		*attr = *f.SysProcAttr
+	}
+	attr.Cloneflags |= flags
+	return attr
+}
+
+// mount sets up ms in our (private) mount namespace
+func mount(ms []Mount) error {
+	// make sure nothing we do propagates back to the parent namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("mount: make / private: %v", err)
+	}
+	for _, m := range ms {
+		if m.Type == "" {
+			m.Type = "bind"
+		}
+		if err := m.mount(); err != nil {
+			return fmt.Errorf("mount %s on %s: %v", m.Type, m.Target, err)
+		}
+	}
+	return nil
+}
+
+func (m Mount) mount() error {
+	var flags uintptr
+	if m.ReadOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	switch m.Type {
+	case "bind":
+		if err := mountpoint(m.Target, m.Source); err != nil {
+			return err
+		}
+		if err := syscall.Mount(m.Source, m.Target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return err
+		}
+		if m.ReadOnly {
+			// bind mounts ignore MS_RDONLY until remounted
+			return syscall.Mount("", m.Target, "", syscall.MS_BIND|syscall.MS_REMOUNT|flags, "")
+		}
+		return nil
+	case "tmpfs":
+		if err := mountpoint(m.Target, ""); err != nil {
+			return err
+		}
+		return syscall.Mount("tmpfs", m.Target, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV|flags, m.Data)
+	case "proc":
+		if err := mountpoint(m.Target, ""); err != nil {
+			return err
+		}
+		return syscall.Mount("proc", m.Target, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC|flags, m.Data)
+	}
+	return fmt.Errorf("unknown mount type %q", m.Type)
+}
+
+// mountpoint makes sure target exists, as a file if source is a file, otherwise as a directory
+func mountpoint(target, source string) error {
+	if _, err := os.Stat(target); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	if source != "" {
+		if fi, err := os.Stat(source); err == nil && !fi.IsDir() {
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			return f.Close()
+		}
+	}
+	return os.MkdirAll(target, 0755)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import (
+	"errors"
+	"syscall"
+)
+
+// sysProcAttr returns the SysProcAttr to start the child with
+func (f *Function) sysProcAttr() *syscall.SysProcAttr {
+	return f.SysProcAttr
+}
+
+func mount(ms []Mount) error {
+	return errors.New("mounts are only supported on Linux")
+}
@@ -0,0 +1,177 @@
+package fork
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// see linux/if_link.h and linux/veth.h
+const (
+	iflaInfoKind = 1
+	iflaInfoData = 2
+	vethInfoPeer = 1
+)
+
+// how long a child waits for its end of a veth pair to show up
+const vethTimeout = 5 * time.Second
+
+// hostVethName is the default host-side name of the veth pair for child pid
+func hostVethName(pid int) string {
+	return "gofork" + strconv.Itoa(pid)
+}
+
+// setupVeth creates the veth pair for a started child, moving its peer into the child's namespace,
+// and configures our end of it.
+func (f *Function) setupVeth() (err error) {
+	v := f.Network.Veth
+	host := v.HostName
+	if host == "" {
+		host = hostVethName(f.Process.Pid)
+	}
+	peer := ifInfomsg(0, 0)
+	peer = append(peer, rtAttr(syscall.IFLA_IFNAME, cString(v.childName()))...)
+	peer = append(peer, rtAttr(syscall.IFLA_NET_NS_PID, nativeUint32(uint32(f.Process.Pid)))...)
+	info := rtAttr(iflaInfoKind, []byte("veth"))
+	info = append(info, rtAttr(iflaInfoData, rtAttr(vethInfoPeer, peer))...)
+	req := ifInfomsg(0, 0)
+	req = append(req, rtAttr(syscall.IFLA_IFNAME, cString(host))...)
+	req = append(req, rtAttr(syscall.IFLA_LINKINFO, info)...)
+	if err = netlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, req); err != nil {
+		return fmt.Errorf("create veth %s: %v", host, err)
+	}
+	return configureLink(host, v.HostAddr)
+}
+
+// network configures the child's side of its new network namespace
+func network(n *Network) (err error) {
+	if err = configureLink("lo", ""); err != nil {
+		return
+	}
+	if n.Veth == nil {
+		return
+	}
+	name := n.Veth.childName()
+	// the parent creates the veth pair once we exist, so it may not be here yet
+	deadline := time.Now().Add(vethTimeout)
+	for {
+		if _, err = net.InterfaceByName(name); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("veth %s never appeared: %v", name, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return configureLink(name, n.Veth.ChildAddr)
+}
+
+func (v *Veth) childName() string {
+	if v.ChildName == "" {
+		return "eth0"
+	}
+	return v.ChildName
+}
+
+// configureLink assigns the (CIDR) address addr, if any, to the named link and brings it up
+func configureLink(name, addr string) (err error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return
+	}
+	if addr != "" {
+		ip, ipnet, err := net.ParseCIDR(addr)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		family := syscall.AF_INET6
+		if ip4 := ip.To4(); ip4 != nil {
+			family, ip = syscall.AF_INET, ip4
+		}
+		ones, _ := ipnet.Mask.Size()
+		ifa := syscall.IfAddrmsg{Family: uint8(family), Prefixlen: uint8(ones), Index: uint32(ifi.Index)}
+		req := append([]byte{}, (*(*[syscall.SizeofIfAddrmsg]byte)(unsafe.Pointer(&ifa)))[:]...)
+		req = append(req, rtAttr(syscall.IFA_LOCAL, ip)...)
+		req = append(req, rtAttr(syscall.IFA_ADDRESS, ip)...)
+		if err = netlinkRequest(syscall.RTM_NEWADDR, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL, req); err != nil {
+			return fmt.Errorf("%s: add address %s: %v", name, addr, err)
+		}
+	}
+	if err = netlinkRequest(syscall.RTM_NEWLINK, 0, ifInfomsg(ifi.Index, syscall.IFF_UP)); err != nil {
+		return fmt.Errorf("%s: set up: %v", name, err)
+	}
+	return
+}
+
+// netlinkRequest sends a single rtnetlink request and waits for it to be acknowledged
+func netlinkRequest(typ uint16, flags uint16, body []byte) (err error) {
+	s, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(s)
+	if err = syscall.Bind(s, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return
+	}
+	h := syscall.NlMsghdr{
+		Len:   uint32(syscall.SizeofNlMsghdr + len(body)),
+		Type:  typ,
+		Flags: syscall.NLM_F_REQUEST | syscall.NLM_F_ACK | flags,
+		Seq:   1,
+	}
+	msg := append((*(*[syscall.SizeofNlMsghdr]byte)(unsafe.Pointer(&h)))[:], body...)
+	if err = syscall.Sendto(s, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return
+	}
+	buf := make([]byte, syscall.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(s, buf, 0)
+		if err != nil {
+			return err
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Seq != h.Seq || m.Header.Type != syscall.NLMSG_ERROR {
+				continue
+			}
+			if len(m.Data) < 4 {
+				return syscall.EINVAL
+			}
+			if errno := -*(*int32)(unsafe.Pointer(&m.Data[0])); errno != 0 {
+				return syscall.Errno(errno)
+			}
+			return nil
+		}
+	}
+}
+
+// ifInfomsg encodes an ifinfomsg for link index, setting (and changing) flags
+func ifInfomsg(index int, flags uint32) []byte {
+	ifi := syscall.IfInfomsg{Family: syscall.AF_UNSPEC, Index: int32(index), Flags: flags, Change: flags}
+	return append([]byte{}, (*(*[syscall.SizeofIfInfomsg]byte)(unsafe.Pointer(&ifi)))[:]...)
+}
+
+// rtAttr encodes a (4-byte aligned) rtattr
+func rtAttr(typ uint16, data []byte) []byte {
+	a := syscall.RtAttr{Len: uint16(syscall.SizeofRtAttr + len(data)), Type: typ}
+	b := append([]byte{}, (*(*[syscall.SizeofRtAttr]byte)(unsafe.Pointer(&a)))[:]...)
+	b = append(b, data...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func nativeUint32(v uint32) []byte {
+	return append([]byte{}, (*(*[4]byte)(unsafe.Pointer(&v)))[:]...)
+}
+
+func cString(s string) []byte {
+	return append([]byte(s), 0)
+}
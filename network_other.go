@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+var errNoNetwork = errors.New("network namespaces are only supported on Linux")
+
+func (f *Function) setupVeth() error {
+	return errNoNetwork
+}
+
+func network(n *Network) error {
+	return errNoNetwork
+}
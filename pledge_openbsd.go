@@ -0,0 +1,53 @@
+package fork
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// pledge and unveil are called through libc, as OpenBSD only allows system calls from libc (see pinsyscalls(2)),
+// the way golang.org/x/sys/unix calls them: the trampolines, in assembly, jump to the libc functions, and
+// syscall.syscall, which the runtime provides, calls them on the system stack.
+
+//go:linkname syscall_syscall syscall.syscall
+func syscall_syscall(fn, a1, a2, a3 uintptr) (r1, r2 uintptr, err syscall.Errno)
+
+var libc_pledge_trampoline_addr uintptr
+
+//go:cgo_import_dynamic libc_pledge pledge "libc.so"
+
+var libc_unveil_trampoline_addr uintptr
+
+//go:cgo_import_dynamic libc_unveil unveil "libc.so"
+
+// pledge restricts the process to promises, see pledge(2)
+func pledge(promises string) error {
+	p, err := syscall.BytePtrFromString(promises)
+	if err != nil {
+		return err
+	}
+	if _, _, e := syscall_syscall(libc_pledge_trampoline_addr, uintptr(unsafe.Pointer(p)), 0, 0); e != 0 {
+		return fmt.Errorf("pledge %q: %v", promises, e)
+	}
+	return nil
+}
+
+// unveil exposes path to the process with permissions, see unveil(2).
+// An empty path locks the unveil list.
+func unveil(path, permissions string) error {
+	var p, q *byte
+	if path != "" {
+		var err error
+		if p, err = syscall.BytePtrFromString(path); err != nil {
+			return err
+		}
+		if q, err = syscall.BytePtrFromString(permissions); err != nil {
+			return err
+		}
+	}
+	if _, _, e := syscall_syscall(libc_unveil_trampoline_addr, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(q)), 0); e != 0 {
+		return fmt.Errorf("unveil %q: %v", path, e)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !openbsd
+// +build !openbsd
+
+package fork
+
+import "errors"
+
+var errNoPledge = errors.New("pledge and unveil are only supported on OpenBSD")
+
+func pledge(promises string) error {
+	return errNoPledge
+}
+
+func unveil(path, permissions string) error {
+	return errNoPledge
+}
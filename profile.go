@@ -0,0 +1,142 @@
+package fork
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Profile is a named policy for children, defined once with DefineProfile and applied to any number of Functions.
+// Settings a Function has made itself take precedence over its profile.
+type Profile struct {
+	// Sandbox restricts what the child can see and do
+	Sandbox Sandbox `json:"sandbox"`
+	// Limits bounds the resources the child can use
+	Limits Limits `json:"limits"`
+	// Env is added to the child's environment, as "key=value" entries
+	Env []string `json:"env,omitempty"`
+}
+
+// Sandbox is the sandboxing part of a Profile; see the Function fields of the same names
+type Sandbox struct {
+	Pledge          string           `json:"pledge,omitempty"`
+	Unveil          []UnveilPath     `json:"unveil,omitempty"`
+	Jail            string           `json:"jail,omitempty"`
+	Capsicum        bool             `json:"capsicum,omitempty"`
+	Landlock        *LandlockRuleset `json:"landlock,omitempty"`
+	Mounts          []Mount          `json:"mounts,omitempty"`
+	Network         *Network         `json:"network,omitempty"`
+	InitShim        bool             `json:"init_shim,omitempty"`
+	Hostname        string           `json:"hostname,omitempty"`
+	PrivateTmp      bool             `json:"private_tmp,omitempty"`
+	SELinuxLabel    string           `json:"selinux_label,omitempty"`
+	AppArmorProfile string           `json:"apparmor_profile,omitempty"`
+}
+
+// Limits is the resource limiting part of a Profile; see the Function fields of the same names
+type Limits struct {
+	MemoryLimit        uint64        `json:"memory_limit,omitempty"`
+	MemoryPollInterval time.Duration `json:"memory_poll_interval,omitempty"`
+	CPULimit           time.Duration `json:"cpu_limit,omitempty"`
+	MaxArgBytes        int64         `json:"max_arg_bytes,omitempty"`
+	GOMAXPROCS         int           `json:"gomaxprocs,omitempty"`
+	GOGC               int           `json:"gogc,omitempty"`
+	CoreDumps          CoreDumpMode  `json:"core_dumps,omitempty"`
+	CPUAffinity        []int         `json:"cpu_affinity,omitempty"`
+}
+
+var (
+	profilesMu sync.Mutex
+	profiles   = map[string]Profile{}
+)
+
+// DefineProfile defines, or redefines, the profile name.
+// Redefining a profile doesn't change the Functions it was already applied to.
+func DefineProfile(name string, p Profile) {
+	profilesMu.Lock()
+	profiles[name] = p
+	profilesMu.Unlock()
+}
+
+// UseProfile applies the profile name to f
+func (f *Function) UseProfile(name string) error {
+	profilesMu.Lock()
+	p, ok := profiles[name]
+	profilesMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no profile by name: %s", name)
+	}
+	p.Apply(f)
+	return nil
+}
+
+// RegisterFuncProfile records a function as a fork in the internal fork map, with the profile name applied.
+// Like RegisterFunc, it is meant for init, and panics if the profile isn't defined.
+func RegisterFuncProfile(n string, fn interface{}, profile string) {
+	f := NewFork(n, fn)
+	if err := f.UseProfile(profile); err != nil {
+		panic(err)
+	}
+	Register(f)
+}
+
+// Apply applies p to f, where f doesn't have settings of its own
+func (p Profile) Apply(f *Function) {
+	s, l := p.Sandbox, p.Limits
+	if f.Pledge == "" {
+		f.Pledge = s.Pledge
+	}
+	if f.Unveil == nil {
+		f.Unveil = s.Unveil
+	}
+	if f.Jail == "" {
+		f.Jail = s.Jail
+	}
+	f.Capsicum = f.Capsicum || s.Capsicum
+	if f.Landlock == nil {
+		f.Landlock = s.Landlock
+	}
+	if f.Mounts == nil {
+		f.Mounts = s.Mounts
+	}
+	if f.Network == nil {
+		f.Network = s.Network
+	}
+	f.InitShim = f.InitShim || s.InitShim
+	if f.Hostname == "" {
+		f.Hostname = s.Hostname
+	}
+	f.PrivateTmp = f.PrivateTmp || s.PrivateTmp
+	if f.SELinuxLabel == "" {
+		f.SELinuxLabel = s.SELinuxLabel
+	}
+	if f.AppArmorProfile == "" {
+		f.AppArmorProfile = s.AppArmorProfile
+	}
+	if f.MemoryLimit == 0 {
+		f.MemoryLimit = l.MemoryLimit
+	}
+	if f.MemoryPollInterval == 0 {
+		f.MemoryPollInterval = l.MemoryPollInterval
+	}
+	if f.CPULimit == 0 {
+		f.CPULimit = l.CPULimit
+	}
+	if f.MaxArgBytes == 0 {
+		f.MaxArgBytes = l.MaxArgBytes
+	}
+	if f.GOMAXPROCS == 0 {
+		f.GOMAXPROCS = l.GOMAXPROCS
+	}
+	if f.GOGC == 0 {
+		f.GOGC = l.GOGC
+	}
+	if f.CoreDumps == CoreDumpsInherit {
+		f.CoreDumps = l.CoreDumps
+	}
+	if f.CPUAffinity == nil {
+		f.CPUAffinity = l.CPUAffinity
+	}
+	// the Function's own entries come last, so they win
+	f.Env = append(append([]string(nil), p.Env...), f.Env...)
+}
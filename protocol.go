@@ -0,0 +1,107 @@
+package fork
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// The invocation protocol, version 1.
+//
+// A child is the same executable as its parent, or another linking this package (see Function.Executable),
+// started with the environment:
+//
+//	GOFORK_PROTOCOL=<version>  the protocol version the invocation follows
+//	GOFORK_NAME=<name>         the name of the registered function to call
+//	GOFORK_ARGS=<path>         a file holding the encoded arguments, which the child removes; if unset, the function takes no arguments
+//
+// and optionally, each naming a descriptor the child inherits:
+//
+//	GOFORK_STATUS=<fd>   the write end of a pipe, on which the child first acknowledges GOFORK_NAME and GOFORK_PROTOCOL,
+//	                     once it has the function and understands the protocol, and then reports why
+//	                     it couldn't call its function, gob encoded
+//	GOFORK_CONTROL=<fd>  a unix socket the child serves gob encoded net/rpc on (see ServeControl)
+//	GOFORK_KEY=<fd>      a pipe carrying the AES-256-GCM key the args file is sealed with (see EncryptArgs)
+//
+// GOFORK_CONTROL comes with GOFORK_CONTROL_VERSION=<version>, the version of the control protocol the parent speaks,
+// currently 1. If it is set, the child starts the control channel with "GFC" and the version byte of the protocol
+// it speaks, and closes it if it doesn't speak the parent's.
+//
+// The args file is a gob stream of the child's settings, then, for each parameter in order,
+// a bool that is true if the argument is nil, followed by the argument if it isn't.
+// Arguments for interface parameters are encoded as the interface type, so gob carries their concrete type.
+// An empty settings record, as WriteArgs writes, leaves the child as it was started.
+//
+// An encrypted args file starts with a 7 byte nonce prefix, followed by the stream sealed in segments of 64KiB
+// (the last may be shorter), each with the nonce of the prefix, the big-endian uint32 segment number, and a byte
+// that is 1 for the last segment, 0 otherwise.
+//
+// When Init finds it was invoked with a protocol version it doesn't support, the child fails with a ChildError
+// naming the version it does support, rather than misreading its arguments. So does a child invoked without
+// GOFORK_PROTOCOL, by a parent from before the protocol was versioned, which wrote its args differently.
+type Protocol struct {
+	// Version is the protocol version
+	Version int
+}
+
+const (
+	// ProtocolVersion is the version of the protocol Fork uses, and the one children understand
+	ProtocolVersion = 1
+
+	protocolVar = "GOFORK_PROTOCOL"
+)
+
+// Supported reports whether children built with this package understand p
+func (p Protocol) Supported() bool {
+	return p.Version == ProtocolVersion
+}
+
+// Env returns the environment entries invoking the function name, with the args file at argsFile (if any)
+func (p Protocol) Env(name, argsFile string) []string {
+	env := []string{
+		protocolVar + "=" + strconv.Itoa(p.Version),
+		nameVar + "=" + name,
+	}
+	if argsFile != "" {
+		env = append(env, argsVar+"="+argsFile)
+	}
+	return env
+}
+
+// WriteArgs validates args against the signature of fn, and writes them to w as the contents of an args file
+func (p Protocol) WriteArgs(w io.Writer, fn interface{}, args ...interface{}) error {
+	if !p.Supported() {
+		return fmt.Errorf("unsupported protocol version: %d", p.Version)
+	}
+	f := NewFork("", fn)
+	if f == nil {
+		return fmt.Errorf("not a function: %T", fn)
+	}
+	if err := f.validateArgs(args...); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(&childConfig{}); err != nil {
+		return err
+	}
+	return encodeArgs(enc, f.fn.Type(), args)
+}
+
+// checkProtocol checks that we, as a child, understand how we were invoked
+func checkProtocol() error {
+	v := os.Getenv(protocolVar)
+	if v == "" {
+		return fmt.Errorf("unversioned invocation, from a parent that predates protocol version %d", ProtocolVersion)
+	}
+	os.Unsetenv(protocolVar)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("bad protocol version: %q", v)
+	}
+	if !(Protocol{Version: n}).Supported() {
+		return fmt.Errorf("unsupported protocol version %d (supported: %d)", n, ProtocolVersion)
+	}
+	return nil
+}
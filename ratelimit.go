@@ -0,0 +1,63 @@
+package fork
+
+import (
+	"sync"
+	"time"
+)
+
+// spawnBucket is a token bucket limiting how fast children are started
+type spawnBucket struct {
+	sync.Mutex
+	rate   float64 // tokens per second, 0 if unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var spawns spawnBucket
+
+// SetSpawnRate limits the children started, by all Functions, to n per interval per, with bursts of up to n.
+// Fork (and ReFork and Replay) fail with ErrRateLimited past the limit, unless WaitForSpawn is set.
+// A non-positive n or per removes the limit.
+func SetSpawnRate(n int, per time.Duration) {
+	spawns.Lock()
+	defer spawns.Unlock()
+	if n <= 0 || per <= 0 {
+		spawns.rate = 0
+		return
+	}
+	spawns.rate = float64(n) / per.Seconds()
+	spawns.burst = float64(n)
+	spawns.tokens = spawns.burst
+	spawns.last = time.Now()
+}
+
+// take takes a token to start a child, waiting for one if wait is set
+func (b *spawnBucket) take(wait bool) error {
+	b.Lock()
+	if b.rate == 0 {
+		b.Unlock()
+		return nil
+	}
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.Unlock()
+		return nil
+	}
+	if !wait {
+		b.Unlock()
+		return ErrRateLimited
+	}
+	// take the token now, and wait until it would have been there
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.Unlock()
+	time.Sleep(delay)
+	return nil
+}
@@ -0,0 +1,51 @@
+package fork
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpawnBucket(t *testing.T) {
+	b := &spawnBucket{rate: 10, burst: 2, tokens: 2, last: time.Now()}
+	for i := 0; i < 2; i++ {
+		if err := b.take(false); err != nil {
+			t.Fatalf("take %d: %v", i+1, err)
+		}
+	}
+	if err := b.take(false); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("take past the burst: got %v, want ErrRateLimited", err)
+	}
+	start := time.Now()
+	if err := b.take(true); err != nil {
+		t.Fatal(err)
+	}
+	// a token comes every 100ms
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("waiting take returned after %v, want about 100ms", d)
+	}
+}
+
+func TestSpawnBucketUnlimited(t *testing.T) {
+	b := &spawnBucket{}
+	for i := 0; i < 100; i++ {
+		if err := b.take(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSetSpawnRate(t *testing.T) {
+	SetSpawnRate(1, time.Hour)
+	defer SetSpawnRate(0, 0)
+	f := NewFork("benchChild", benchChild)
+	if err := f.Fork([]byte(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ReFork([]byte(nil)); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second fork: got %v, want ErrRateLimited", err)
+	}
+}
@@ -0,0 +1,145 @@
+package fork
+
+import (
+	"sync"
+	"time"
+)
+
+// exitWatch is closed once a child has exited
+type exitWatch struct {
+	ch   chan struct{}
+	once sync.Once
+	// the child to watch, with a goroutine of its own once Done is first called;
+	// 0 if the reaper watches it instead, or for a function forked in process
+	pid   int
+	start sync.Once
+	// when it was closed, the child's exit time as near as we know it
+	at time.Time
+}
+
+func newExitWatch() *exitWatch {
+	return &exitWatch{ch: make(chan struct{})}
+}
+
+// watch starts the goroutine waiting for the child to exit
+func (w *exitWatch) watch() {
+	go func() {
+		if waitExit(w.pid) {
+			w.close()
+		}
+	}()
+}
+
+func (w *exitWatch) close() {
+	w.once.Do(func() {
+		w.at = time.Now()
+		close(w.ch)
+	})
+}
+
+// the reaper, if enabled with EnableReaper: the children it watches, by pid
+var reaper struct {
+	sync.Mutex
+	enabled bool
+	pids    map[int]*exitWatch
+}
+
+// EnableReaper watches all children for their exit with a single goroutine, in place of a goroutine (and the OS thread
+// it blocks) per child that Done or Wait is called for: Wait then waits for the reaper to see the child exit before it
+// reaps it. It is meant for when thousands of children are in flight.
+// The reaper waits on a pidfd per child; before Linux 5.3 it is woken by SIGCHLD instead, and checks every child each time.
+// Children already started keep their own watcher (Linux only).
+func EnableReaper() {
+	reaper.Lock()
+	defer reaper.Unlock()
+	if reaper.enabled || !startReaper() {
+		return
+	}
+	reaper.enabled = true
+	reaper.pids = make(map[int]*exitWatch)
+}
+
+// Done returns a channel that is closed when the child has exited, or the function forked in process has returned,
+// after which Wait returns straight away. It is nil before the first Fork.
+// On platforms where exits can't be watched for, it is closed by Wait.
+func (f *Function) Done() <-chan struct{} {
+	w := f.exit
+	if w == nil {
+		return nil
+	}
+	if w.pid != 0 {
+		w.start.Do(w.watch)
+	}
+	return w.ch
+}
+
+// watchExit starts watching the child for its exit, for Done
+func (f *Function) watchExit() {
+	w := newExitWatch()
+	f.exit = w
+	pid := f.Process.Pid
+	reaper.Lock()
+	if !reaper.enabled {
+		reaper.Unlock()
+		w.pid = pid
+		return
+	}
+	reaper.pids[pid] = w
+	own := watchPid(pid)
+	reaper.Unlock()
+	// it may have exited before we were watching
+	if !own && exited(pid) {
+		reaped(pid)
+	}
+}
+
+// waitReaped waits until the reaper has seen the child exit, if it watches the child,
+// so Wait's own wait for it returns straight away, rather than block an OS thread
+func (f *Function) waitReaped() {
+	if w := f.exit; w != nil && w.pid == 0 {
+		<-w.ch
+	}
+}
+
+// reaped closes the exit watch of the child pid, which has exited
+func reaped(pid int) {
+	reaper.Lock()
+	w := reaper.pids[pid]
+	delete(reaper.pids, pid)
+	unwatchPid(pid)
+	reaper.Unlock()
+	if w != nil {
+		w.close()
+	}
+}
+
+// reap closes the exit watches of the children that have exited, when the reaper is woken by SIGCHLD
+func reap() {
+	reaper.Lock()
+	var done []int
+	for pid := range reaper.pids {
+		if exited(pid) {
+			done = append(done, pid)
+		}
+	}
+	reaper.Unlock()
+	for _, pid := range done {
+		reaped(pid)
+	}
+}
+
+// stopWatchExit closes the exit watch once the child has been waited for, so its pid can't be mistaken for another
+func (f *Function) stopWatchExit() {
+	if f.exit == nil {
+		return
+	}
+	if f.Process != nil {
+		reaper.Lock()
+		if reaper.pids[f.Process.Pid] == f.exit {
+			delete(reaper.pids, f.Process.Pid)
+			unwatchPid(f.Process.Pid)
+		}
+		reaper.Unlock()
+	}
+	f.exit.close()
+}
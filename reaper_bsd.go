@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package fork
+
+import "syscall"
+
+// waitExit waits for the child pid to exit, leaving it for Wait to reap
+func waitExit(pid int) bool {
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return false
+	}
+	defer syscall.Close(kq)
+	var ev syscall.Kevent_t
+	syscall.SetKevent(&ev, pid, syscall.EVFILT_PROC, syscall.EV_ADD|syscall.EV_ONESHOT)
+	ev.Fflags = syscall.NOTE_EXIT
+	for {
+		_, err = syscall.Kevent(kq, []syscall.Kevent_t{ev}, make([]syscall.Kevent_t, 1), nil)
+		if err != syscall.EINTR {
+			// ESRCH: it has exited, or been waited for, already
+			return err == nil || err == syscall.ESRCH
+		}
+	}
+}
+
+func exited(pid int) bool { return false }
+
+// stops can't be watched for here
+func waitStopped(pid int) bool { return false }
+
+func startReaper() bool { return false }
+
+func watchPid(pid int) bool { return false }
+
+func unwatchPid(pid int) {}
@@ -0,0 +1,156 @@
+package fork
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// from <sys/wait.h>, and the syscall number, the same on every architecture
+const (
+	pPID    = 1
+	wNOWAIT = 0x01000000
+
+	sysPidfdOpen = 434
+)
+
+// the epoll instance the reaper waits on, with a pidfd per child, or -1 when pidfds aren't supported (before Linux 5.3)
+// and SIGCHLD wakes the reaper to check all children instead
+var reaperEpoll = -1
+
+// the pidfds of the children the reaper watches, by pid; guarded by reaper
+var pidfds = map[int]int{}
+
+// waitid waits for the child pid to exit, without reaping it, or checks if it has with nohang
+func waitid(pid int, nohang bool) (bool, error) {
+	options := syscall.WEXITED | wNOWAIT
+	if nohang {
+		options |= syscall.WNOHANG
+	}
+	return waitidOptions(pid, options)
+}
+
+// waitStopped waits for the child pid to stop, consuming the stop, or to exit, leaving it for Wait to reap,
+// and reports whether it stopped
+func waitStopped(pid int) bool {
+	for {
+		if _, err := waitidOptions(pid, syscall.WEXITED|syscall.WSTOPPED|wNOWAIT); err != nil {
+			return false
+		}
+		if stopped, _ := waitidOptions(pid, syscall.WSTOPPED|syscall.WNOHANG); stopped {
+			return true
+		}
+		if exited(pid) {
+			return false
+		}
+		// it was continued before we got to its stop
+	}
+}
+
+// waitidOptions calls waitid(2) on the child pid, and reports whether it had a state change to report
+func waitidOptions(pid int, options int) (bool, error) {
+	// siginfo_t; si_signo is left 0 if there was no state change
+	var info [128]byte
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_WAITID, pPID, uintptr(pid), uintptr(unsafe.Pointer(&info[0])), uintptr(options), 0, 0)
+		switch e {
+		case 0:
+			return *(*int32)(unsafe.Pointer(&info[0])) != 0, nil
+		case syscall.EINTR:
+			continue
+		}
+		return false, e
+	}
+}
+
+// waitExit waits for the child pid to exit, leaving it for Wait to reap
+func waitExit(pid int) bool {
+	_, err := waitid(pid, false)
+	// ECHILD: Wait got to it first
+	return err == nil || err == syscall.ECHILD
+}
+
+// exited reports whether the child pid has exited, leaving it for Wait to reap
+func exited(pid int) bool {
+	ok, err := waitid(pid, true)
+	return ok || err == syscall.ECHILD
+}
+
+// pidfdOpen returns a pidfd for pid, which polls readable once it has exited
+func pidfdOpen(pid int) (int, error) {
+	fd, _, e := syscall.Syscall(sysPidfdOpen, uintptr(pid), 0, 0)
+	if e != 0 {
+		return -1, e
+	}
+	syscall.CloseOnExec(int(fd))
+	return int(fd), nil
+}
+
+// startReaper starts the reaper goroutine, waiting on the pidfds of the children, or else woken by SIGCHLD
+func startReaper() bool {
+	if fd, err := pidfdOpen(os.Getpid()); err == nil {
+		syscall.Close(fd)
+		if reaperEpoll, err = syscall.EpollCreate1(syscall.EPOLL_CLOEXEC); err == nil {
+			go reapPidfds()
+			return true
+		}
+		reaperEpoll = -1
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGCHLD)
+	go func() {
+		for range c {
+			reap()
+		}
+	}()
+	return true
+}
+
+// reapPidfds closes the exit watches of the children whose pidfds poll readable
+func reapPidfds() {
+	events := make([]syscall.EpollEvent, 64)
+	for {
+		n, err := syscall.EpollWait(reaperEpoll, events, -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		for _, ev := range events[:n] {
+			// the pid may have been waited for, and taken by a new child, since the event
+			if pid := int(ev.Fd); exited(pid) {
+				reaped(pid)
+			}
+		}
+	}
+}
+
+// watchPid has the reaper watch the child pid, and reports whether it does so on its own, or only checks for it
+// when woken by SIGCHLD. It is called with reaper locked.
+func watchPid(pid int) bool {
+	if reaperEpoll < 0 {
+		return false
+	}
+	fd, err := pidfdOpen(pid)
+	if err != nil {
+		return false
+	}
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(pid)}
+	if err = syscall.EpollCtl(reaperEpoll, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		syscall.Close(fd)
+		return false
+	}
+	pidfds[pid] = fd
+	return true
+}
+
+// unwatchPid stops watching the child pid. It is called with reaper locked.
+func unwatchPid(pid int) {
+	if fd, ok := pidfds[pid]; ok {
+		// closing it takes it out of the epoll set
+		syscall.Close(fd)
+		delete(pidfds, pid)
+	}
+}
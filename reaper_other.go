@@ -0,0 +1,15 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package fork
+
+// exits can't be watched for here, Wait closes Done
+func waitExit(pid int) bool { return false }
+
+func exited(pid int) bool { return false }
+
+func startReaper() bool { return false }
+
+func watchPid(pid int) bool { return false }
+
+func unwatchPid(pid int) {}
@@ -0,0 +1,22 @@
+package fork
+
+import "syscall"
+
+// waitExit waits for the child pid to exit
+func waitExit(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	_, err = syscall.WaitForSingleObject(h, syscall.INFINITE)
+	return err == nil
+}
+
+func exited(pid int) bool { return false }
+
+func startReaper() bool { return false }
+
+func watchPid(pid int) bool { return false }
+
+func unwatchPid(pid int) {}
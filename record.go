@@ -0,0 +1,160 @@
+package fork
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files in a recording directory
+const (
+	recordArgs   = "args"
+	recordStdout = "stdout"
+	recordStderr = "stderr"
+	recordMeta   = "meta.json"
+)
+
+// recordMetadata describes a recorded fork
+type recordMetadata struct {
+	Name   string    `json:"name"`
+	Time   time.Time `json:"time"`
+	Pid    int       `json:"pid,omitempty"`
+	Status string    `json:"status,omitempty"`
+	Err    string    `json:"err,omitempty"`
+}
+
+// recording is a fork being recorded
+type recording struct {
+	dir    string
+	meta   recordMetadata
+	args   *os.File
+	stdout *os.File
+	stderr *os.File
+}
+
+// startRecording creates the recording directory for the fork about to start, if we're recording,
+// and tees the child's output into it.
+func (f *Function) startRecording() (err error) {
+	f.recording = nil
+	if f.RecordDir == "" {
+		return
+	}
+	if err = os.MkdirAll(f.RecordDir, 0700); err != nil {
+		return
+	}
+	dir, err := ioutil.TempDir(f.RecordDir, strings.Replace(f.Name, string(filepath.Separator), "_", -1)+"-")
+	if err != nil {
+		return
+	}
+	r := &recording{dir: dir, meta: recordMetadata{Name: f.Name, Time: time.Now()}}
+	for _, file := range []struct {
+		name string
+		f    **os.File
+	}{{recordArgs, &r.args}, {recordStdout, &r.stdout}, {recordStderr, &r.stderr}} {
+		if *file.f, err = os.OpenFile(filepath.Join(dir, file.name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600); err != nil {
+			r.close()
+			return
+		}
+	}
+	// so there's something to replay, even if we never get to Wait
+	r.writeMetadata()
+	f.recording = r
+	f.Command.Stdout = addWriter(f.Command.Stdout, r.stdout)
+	f.Command.Stderr = addWriter(f.Command.Stderr, r.stderr)
+	return
+}
+
+// stopRecording finishes the recording, once the child has exited (or failed to start)
+func (f *Function) stopRecording(err error) {
+	r := f.recording
+	if r == nil {
+		return
+	}
+	f.recording = nil
+	r.close()
+	if f.Command.Process != nil {
+		r.meta.Pid = f.Command.Process.Pid
+	}
+	if ps := f.Command.ProcessState; ps != nil {
+		r.meta.Status = ps.String()
+	}
+	if err != nil {
+		r.meta.Err = err.Error()
+	}
+	r.writeMetadata()
+}
+
+// writeMetadata (re)writes the metadata file of the recording
+func (r *recording) writeMetadata() {
+	if b, err := json.MarshalIndent(&r.meta, "", "  "); err == nil {
+		ioutil.WriteFile(filepath.Join(r.dir, recordMeta), append(b, '\n'), 0600)
+	}
+}
+
+func (r *recording) close() {
+	for _, file := range []*os.File{r.args, r.stdout, r.stderr} {
+		if file != nil {
+			file.Close()
+		}
+	}
+}
+
+// Replay re-runs the fork recorded in dir (see RecordDir), with the same arguments, and waits for it.
+// The function must be registered under the recorded name. The child's output goes to our own.
+// With ForceInProcess, the function is called in this process instead.
+//
+// The child is launched as the registered Function would be, with the namespaces that the recorded child-side
+// settings (mounts, hostname, network, ...) need, so the replay applies them in namespaces of its own as the original did.
+// Secret arguments (see SecretString) aren't recorded, they are replayed as zero values. Recordings of forks that were
+// passed connections can't be replayed: only a reference to the descriptor the child inherited was recorded.
+func Replay(dir string) (err error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, recordMeta))
+	if err != nil {
+		return
+	}
+	var meta recordMetadata
+	if err = json.Unmarshal(b, &meta); err != nil {
+		return fmt.Errorf("bad recording %s: %v", dir, err)
+	}
+	rf, ok := forks[meta.Name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, meta.Name)
+	}
+	args, err := ioutil.ReadFile(filepath.Join(dir, recordArgs))
+	if err != nil {
+		return
+	}
+	// decode it all first, as the child would, but without taking connection references for descriptors of ours
+	dec := gob.NewDecoder(bytes.NewReader(args))
+	var cfg childConfig
+	if err = dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("bad recording %s: decode config: %v", dir, err)
+	}
+	if _, err = decodeArgs(dec, rf.fn.Type(), false); err != nil {
+		return fmt.Errorf("can't replay %s: %v", dir, err)
+	}
+	f := rf.clone()
+	if forcedInProcess() {
+		// skip the child config, it isn't applied in process
+		dec = gob.NewDecoder(bytes.NewReader(args))
+		dec.Decode(&childConfig{})
+		return f.callDecoded(dec)
+	}
+	// the namespaces come from the settings of the Function, which must match those the child applies
+	f.Mounts, f.PrivateTmp, f.Hostname, f.Network = cfg.Mounts, false, cfg.Hostname, cfg.Network
+	f.RecordDir = ""
+	f.Stdin, f.Stdout, f.Stderr = os.Stdin, os.Stdout, os.Stderr
+	f.StdoutStream, f.StderrStream = nil, nil
+	err = f.launch(nil, nil, bytes.NewReader(args))
+	f.audit("fork", err)
+	if err != nil {
+		return
+	}
+	return f.Wait()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 )
 
 // the registry of forks we know about
@@ -13,8 +14,14 @@ var forks map[string]*Function
 const (
 	nameVar = "GOFORK_NAME"
 	argsVar = "GOFORK_ARGS"
+	initVar = "GOFORK_INIT"
+	// set by the init shim for the fork it runs, to the shim's pid as the parent sees it
+	hostPidVar = "GOFORK_PID"
 )
 
+// hostPid is our pid as our parent knows it, which isn't ours under an init shim
+var hostPid int
+
 func init() {
 	forks = make(map[string]*Function)
 }
@@ -25,6 +32,12 @@ func Register(f *Function) {
 	if f.Name == "" {
 		panic("tried to register fork with no name")
 	}
+	if f.VerifyExecutable && os.Getenv(nameVar) == "" {
+		// failing here leaves the digest to be recorded by the first fork
+		if path, err := f.executablePath(); err == nil {
+			recordExecutable(path)
+		}
+	}
 	forks[f.Name] = f
 }
 
@@ -47,47 +60,96 @@ func Init() {
 		// no func is defined
 		return
 	}
+	// when changing labels, this doesn't return
+	initLabel()
+	if os.Getenv(initVar) != "" {
+		// when acting as init, this doesn't return
+		initShim()
+	}
 	os.Unsetenv(nameVar)
+	initHostPid()
+	initStatus()
+	initControl()
+	initListenFiles()
+	if err := checkProtocol(); err != nil {
+		fail(err.Error(), "fork failed: "+err.Error())
+	}
 	// we appear to be a fork
 	if f, ok := forks[name]; ok {
+		sendStatus(&statusMessage{Ack: name, Protocol: ProtocolVersion})
 		v := f.fn
 		t := v.Type()
 		args := []reflect.Value{}
+		cfg := childConfig{}
 		if argsFile := os.Getenv(argsVar); argsFile != "" {
 			// get our arguments
 			f, err := os.Open(argsFile)
 			if err != nil {
-				panic("failed to open args file: " + err.Error())
+				fail("open args: "+err.Error(), "failed to open args file: "+err.Error())
+			}
+			r, err := argsReader(f)
+			if err != nil {
+				fail("decrypt args: "+err.Error(), "failed to decrypt args file: "+err.Error())
+			}
+			dec := gob.NewDecoder(r)
+			if err := dec.Decode(&cfg); err != nil {
+				fail("decode config: "+err.Error(), "failed to decode fork config from args file: "+err.Error())
 			}
-			dec := gob.NewDecoder(f)
-			for i := 0; i < t.NumIn(); i++ {
-				v := reflect.Indirect(reflect.New(t.In(i)))
-				err := dec.DecodeValue(v)
-				if err != nil {
-					panic("failed to decode arguments from args file: " + err.Error())
-				}
-				args = append(args, v)
+			if args, err = decodeArgs(dec, t, true); err != nil {
+				fail(err.Error(), "failed to decode arguments from args file: "+err.Error())
 			}
 			f.Close()
 			os.Remove(argsFile)
 			os.Unsetenv(argsVar)
 		}
 		if t.NumIn() != len(args) {
-			panic("fork failed: incorrect number of args supplied")
+			fail("incorrect number of args supplied", "fork failed: incorrect number of args supplied")
 		}
-		if err := v.Call(args); err != nil {
+		if err := cfg.apply(); err != nil {
+			fail(err.Error(), "fork failed: "+err.Error())
+		}
+		sendStatus(&statusMessage{Ready: true})
+		if err := callChild(v, args); err != nil {
+			stopManagement()
 			os.Exit(1)
 		}
+		stopManagement()
 		os.Exit(0)
 	}
-	panic("no fork by name: " + name)
+	fail("unknown function '"+name+"'", "no fork by name: "+name)
+}
+
+// initHostPid picks up the pid our parent knows us by
+func initHostPid() {
+	hostPid = os.Getpid()
+	if v := os.Getenv(hostPidVar); v != "" {
+		os.Unsetenv(hostPidVar)
+		if pid, err := strconv.Atoi(v); err == nil {
+			hostPid = pid
+		}
+	}
+}
+
+// callChild calls the function of a child, telling the parent if it panics.
+// The panic isn't recovered, so it crashes the child just as it would, trace and all.
+func callChild(v reflect.Value, args []reflect.Value) []reflect.Value {
+	returned := false
+	defer func() {
+		if !returned {
+			sendStatus(&statusMessage{Panic: true})
+			stopManagement()
+		}
+	}()
+	out := v.Call(args)
+	returned = true
+	return out
 }
 
 // Fork calls a registered fork
 func Fork(name string, args ...interface{}) (err error) {
 	f, ok := forks[name]
 	if !ok {
-		return fmt.Errorf("no registered function by name: %s", name)
+		return fmt.Errorf("%w: %s", ErrNotRegistered, name)
 	}
 	return f.Fork(args...)
 }
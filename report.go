@@ -0,0 +1,94 @@
+package fork
+
+import "time"
+
+// A Report is the accounting record of one run of a Function, available from Report once Wait returns.
+type Report struct {
+	// Name is the name of the fork
+	Name string `json:"name"`
+	// Pid is the process id the child ran as
+	Pid int `json:"pid"`
+	// QueueTime is the time between Fork being called and the child being started (validation, encoding and setup)
+	QueueTime time.Duration `json:"queue_time"`
+	// ExecLatency is the time it took to start the child process
+	ExecLatency time.Duration `json:"exec_latency"`
+	// ArgBytes is the size of the args file, as MaxArgBytes measures it
+	ArgBytes int64 `json:"arg_bytes"`
+	// WallTime is the time from the child starting until it exited, as seen by the reaper (see EnableReaper) or Done,
+	// or else until it was waited for
+	WallTime time.Duration `json:"wall_time"`
+	// UserTime and SystemTime are the CPU time used by the child
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	// MaxRSS is the peak resident set size of the child in bytes, if known
+	MaxRSS int64 `json:"max_rss"`
+	// ExitCode is the exit code of the child, or -1 if it was killed by a signal
+	ExitCode int `json:"exit_code"`
+	// Status is the exit status as text, e.g. "exit status 1" or "signal: killed"
+	Status string `json:"status"`
+	// CoreFile is where the child's core dump was moved to (see CoreDir), if it left one
+	CoreFile string `json:"core_file,omitempty"`
+	// CoreError says why the child's core dump couldn't be moved to CoreDir, if it dumped one
+	CoreError string `json:"core_error,omitempty"`
+	// StdoutLogs and StderrLogs are the files the child's stdout and stderr were logged to, in order (see LogDir)
+	StdoutLogs []string `json:"stdout_logs,omitempty"`
+	StderrLogs []string `json:"stderr_logs,omitempty"`
+}
+
+// Report returns the accounting record for the last run of the Function.
+// It returns nil until Wait has returned.
+func (f *Function) Report() *Report {
+	return f.report
+}
+
+// accounting holds what we measure while a child runs, to build its Report
+type accounting struct {
+	forked    time.Time
+	started   time.Time
+	running   time.Time
+	argBytes  int64
+	argDigest string
+}
+
+// exitTime is when the child was seen to exit
+func (f *Function) exitTime() time.Time {
+	if w := f.exit; w != nil {
+		select {
+		case <-w.ch:
+			return w.at
+		default:
+		}
+	}
+	return time.Now()
+}
+
+// buildReport builds the Report for the finished child
+func (f *Function) buildReport() {
+	ps := f.Command.ProcessState
+	if ps == nil {
+		return
+	}
+	a := f.accounting
+	f.report = &Report{
+		Name:        f.Name,
+		Pid:         ps.Pid(),
+		QueueTime:   a.started.Sub(a.forked),
+		ExecLatency: a.running.Sub(a.started),
+		ArgBytes:    a.argBytes,
+		WallTime:    f.exitTime().Sub(a.running),
+		UserTime:    ps.UserTime(),
+		SystemTime:  ps.SystemTime(),
+		MaxRSS:      maxRSS(ps),
+		ExitCode:    ps.ExitCode(),
+		Status:      ps.String(),
+		CoreFile:    f.coreFile,
+	}
+	if f.coreErr != nil {
+		f.report.CoreError = f.coreErr.Error()
+	}
+	if sig, ok := f.exitSignal(ps); ok && ps.Exited() {
+		// the init shim's stand-in for the signal
+		f.report.ExitCode, f.report.Status = -1, "signal: "+sig.String()
+	}
+	f.report.StdoutLogs, f.report.StderrLogs = f.logPaths()
+}
@@ -0,0 +1,21 @@
+//go:build aix || darwin || linux || netbsd || openbsd || solaris
+// +build aix darwin linux netbsd openbsd solaris
+
+package fork
+
+import "syscall"
+
+// setrlimit sets both the soft and hard limits of resource to v
+func setrlimit(resource int, v uint64) error {
+	return syscall.Setrlimit(resource, &syscall.Rlimit{Cur: v, Max: v})
+}
+
+// raiseRlimit raises the soft limit of resource as far as the hard limit allows
+func raiseRlimit(resource int) error {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &rl); err != nil {
+		return err
+	}
+	rl.Cur = rl.Max
+	return syscall.Setrlimit(resource, &rl)
+}
@@ -0,0 +1,64 @@
+package fork
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// descendantsRSS returns the resident set size of the processes below pid, in bytes, as for an init shim:
+// everything in its PID namespace is its descendant.
+// Shared pages are counted once for every process that maps them.
+func descendantsRSS(pid int) (total uint64, err error) {
+	tasks, err := ioutil.ReadDir("/proc/" + strconv.Itoa(pid) + "/task")
+	if err != nil {
+		return
+	}
+	for _, t := range tasks {
+		// every thread has its own children
+		b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/task/" + t.Name() + "/children")
+		if err != nil {
+			if os.IsNotExist(err) {
+				// it has exited, or the kernel lacks CONFIG_PROC_CHILDREN, which checkWatchdogs finds
+				continue
+			}
+			return 0, err
+		}
+		for _, s := range strings.Fields(string(b)) {
+			child, err := strconv.Atoi(s)
+			if err != nil {
+				continue
+			}
+			// it may be a zombie, or gone already
+			if rss, err := processRSS(child); err == nil {
+				total += rss
+			}
+			below, _ := descendantsRSS(child)
+			total += below
+		}
+	}
+	return
+}
+
+// processRSS returns the resident set size of process pid, in bytes
+func processRSS(pid int) (uint64, error) {
+	f, err := os.Open("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// VmRSS:	    1234 kB
+		fields := strings.Fields(s.Text())
+		if len(fields) == 3 && fields[0] == "VmRSS:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			return kb * 1024, err
+		}
+	}
+	// zombies have no VmRSS
+	return 0, errors.New("no VmRSS for process " + strconv.Itoa(pid))
+}
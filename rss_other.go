@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+func descendantsRSS(pid int) (uint64, error) {
+	return processRSS(pid)
+}
+
+func processRSS(pid int) (uint64, error) {
+	return 0, errors.New("reading process memory use is only supported on Linux")
+}
@@ -0,0 +1,32 @@
+package fork
+
+import "strconv"
+
+// LowMemoryChild tunes the Go runtime of the child for a small footprint: a single P, and a collector running twice as often.
+// It's meant for the many-small-workers case, where children inheriting our settings would oversubscribe the machine.
+func (f *Function) LowMemoryChild() {
+	f.GOMAXPROCS = 1
+	f.GOGC = 50
+}
+
+// runtimeEnv adds the Go runtime settings for the child to env
+func (f *Function) runtimeEnv(env []string) []string {
+	if f.GOMAXPROCS > 0 {
+		env = setEnv(env, "GOMAXPROCS", strconv.Itoa(f.GOMAXPROCS))
+	}
+	switch {
+	case f.GOGC > 0:
+		env = setEnv(env, "GOGC", strconv.Itoa(f.GOGC))
+	case f.GOGC < 0:
+		env = setEnv(env, "GOGC", "off")
+	}
+	switch {
+	case f.GOTRACEBACK != "":
+		env = setEnv(env, "GOTRACEBACK", f.GOTRACEBACK)
+	case f.CoreDumps == CoreDumpsOn:
+		env = setEnv(env, "GOTRACEBACK", "crash")
+	case f.CaptureCrash:
+		env = setEnv(env, "GOTRACEBACK", "all")
+	}
+	return env
+}
@@ -0,0 +1,134 @@
+package fork
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduled is a fork set to start later, by ForkAt or ForkAfter; see ForkEvery for one that repeats
+type Scheduled struct {
+	timer   *time.Timer
+	started chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// ForkAt forks f with args at t, or straight away if t has passed. The args are validated now, and encoded when f forks.
+// Once Started is closed, Err says how Fork went, and if it went well, the child is waited for as usual.
+func (f *Function) ForkAt(t time.Time, args ...interface{}) (*Scheduled, error) {
+	return f.ForkAfter(time.Until(t), args...)
+}
+
+// ForkAfter forks f with args once d has passed, as ForkAt does
+func (f *Function) ForkAfter(d time.Duration, args ...interface{}) (*Scheduled, error) {
+	if err := f.validateArgs(args...); err != nil {
+		return nil, err
+	}
+	s := &Scheduled{started: make(chan struct{})}
+	s.timer = time.AfterFunc(d, func() {
+		s.once.Do(func() {
+			s.err = f.Fork(args...)
+			close(s.started)
+		})
+	})
+	return s, nil
+}
+
+// Cancel keeps the fork from starting, and reports whether it did; if it had already started, it is left running
+func (s *Scheduled) Cancel() bool {
+	canceled := false
+	s.once.Do(func() {
+		s.timer.Stop()
+		s.err = ErrCanceled
+		canceled = true
+		close(s.started)
+	})
+	return canceled
+}
+
+// Started returns a channel that is closed once Fork has been called, or the fork was canceled
+func (s *Scheduled) Started() <-chan struct{} {
+	return s.started
+}
+
+// Err returns the error from Fork, or ErrCanceled, once Started is closed; nil before
+func (s *Scheduled) Err() error {
+	select {
+	case <-s.started:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Periodic is a fork run over and over, by ForkEvery
+type Periodic struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	mu       sync.Mutex
+	runs     int
+	err      error
+}
+
+// ForkEvery forks f with args every d, the first time once d has passed, ReForking it for each run after a child
+// has been started and waited for. Runs don't overlap: one that takes longer than d is followed straight away.
+// The args are validated now, and encoded for every run. f must be left alone until Stop returns.
+func (f *Function) ForkEvery(d time.Duration, args ...interface{}) (*Periodic, error) {
+	if err := f.validateArgs(args...); err != nil {
+		return nil, err
+	}
+	p := &Periodic{stop: make(chan struct{}), done: make(chan struct{})}
+	go p.run(f, d, args)
+	return p, nil
+}
+
+func (p *Periodic) run(f *Function, d time.Duration, args []interface{}) {
+	defer close(p.done)
+	next := time.Now().Add(d)
+	for {
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-p.stop:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		next = time.Now().Add(d)
+		var err error
+		// Fork until a child has been started, which a Fork that failed early didn't
+		if f.Command.Process == nil {
+			err = f.Fork(args...)
+		} else {
+			err = f.ReFork(args...)
+		}
+		if err == nil {
+			err = f.Wait()
+		}
+		p.mu.Lock()
+		p.runs++
+		p.err = err
+		p.mu.Unlock()
+	}
+}
+
+// Stop keeps any more runs from starting, waits for the one in progress, if any, to finish, and returns what Last does
+func (p *Periodic) Stop() (runs int, err error) {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+	return p.Last()
+}
+
+// Runs returns how many runs have finished
+func (p *Periodic) Runs() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runs
+}
+
+// Last returns how many runs have finished, and the error from Fork or Wait of the last one
+func (p *Periodic) Last() (runs int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runs, p.err
+}
@@ -0,0 +1,119 @@
+package fork
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestForkAfter(t *testing.T) {
+	f := NewFork("benchChild", benchChild)
+	start := time.Now()
+	s, err := f.ForkAfter(50*time.Millisecond, []byte(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Err() != nil {
+		t.Errorf("Err before the fork: %v", s.Err())
+	}
+	<-s.Started()
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("started after %v, want 50ms", d)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Cancel() {
+		t.Error("Cancel of a started fork reported it canceled")
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForkAtPast(t *testing.T) {
+	f := NewFork("benchChild", benchChild)
+	s, err := f.ForkAt(time.Now().Add(-time.Hour), []byte(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-s.Started():
+	case <-time.After(5 * time.Second):
+		t.Fatal("a fork due in the past didn't start")
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForkAfterCancel(t *testing.T) {
+	f := NewFork("benchChild", benchChild)
+	s, err := f.ForkAfter(time.Hour, []byte(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Cancel() {
+		t.Fatal("Cancel didn't cancel")
+	}
+	<-s.Started()
+	if err := s.Err(); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Err: got %v, want ErrCanceled", err)
+	}
+	if s.Cancel() {
+		t.Error("second Cancel reported it canceled")
+	}
+}
+
+func TestForkAfterBadArgs(t *testing.T) {
+	f := NewFork("benchChild", benchChild)
+	if _, err := f.ForkAfter(time.Hour, "not bytes"); !errors.Is(err, ErrArgType) {
+		t.Errorf("got %v, want ErrArgType", err)
+	}
+}
+
+func TestForkEvery(t *testing.T) {
+	f := NewFork("benchChild", benchChild)
+	p, err := f.ForkEvery(10*time.Millisecond, []byte(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); p.Runs() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d runs", p.Runs())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	runs, err := p.Stop()
+	if err != nil || runs < 3 {
+		t.Fatalf("Stop: %d runs, %v", runs, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if p.Runs() != runs {
+		t.Errorf("%d runs after Stop returned %d", p.Runs(), runs)
+	}
+}
+
+func TestForkEveryFirstForkFails(t *testing.T) {
+	// the first runs are rate limited, before their child can start
+	SetSpawnRate(1, 100*time.Millisecond)
+	defer SetSpawnRate(0, 0)
+	spawns.take(false)
+	f := NewFork("benchChild", benchChild)
+	p, err := f.ForkEvery(10*time.Millisecond, []byte(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no run succeeded")
+		}
+		if runs, err := p.Last(); runs > 0 && err == nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package fork
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Schema describes the parameters and results of a Function, for tools that build or check its arguments,
+// e.g. the submission forms of a job server (see Serve). Go doesn't keep the names of parameters, so they're by position.
+type Schema struct {
+	Name    string       `json:"name"`
+	Params  []TypeSchema `json:"params"`
+	Results []TypeSchema `json:"results,omitempty"`
+	// Variadic says the last parameter is variadic, its arg given as a slice
+	Variadic bool `json:"variadic,omitempty"`
+}
+
+// TypeSchema describes a type
+type TypeSchema struct {
+	// Type is the Go type, e.g. "[]string", and Kind its kind, e.g. "slice"
+	Type string `json:"type"`
+	Kind string `json:"kind"`
+	// Nilable says a nil arg can be given
+	Nilable bool `json:"nilable,omitempty"`
+	// Secret says the arg is redacted (see SecretString), and Conn that it is a connection (see Fork)
+	Secret bool `json:"secret,omitempty"`
+	Conn   bool `json:"conn,omitempty"`
+	// Elem is the element type of arrays, channels, maps, pointers and slices, Key the key type of maps,
+	// and Len the length of arrays
+	Elem *TypeSchema `json:"elem,omitempty"`
+	Key  *TypeSchema `json:"key,omitempty"`
+	Len  int         `json:"len,omitempty"`
+	// Fields are the exported fields of structs, the ones that are passed to the child
+	Fields []FieldSchema `json:"fields,omitempty"`
+}
+
+// FieldSchema describes a struct field
+type FieldSchema struct {
+	Name string `json:"name"`
+	TypeSchema
+}
+
+// Schema returns the Schema of f
+func (f *Function) Schema() Schema {
+	t := f.fn.Type()
+	s := Schema{Name: f.Name, Params: []TypeSchema{}, Variadic: t.IsVariadic()}
+	for i := 0; i < t.NumIn(); i++ {
+		ts := typeSchema(t.In(i), map[reflect.Type]bool{})
+		ts.Secret = f.secret(i)
+		s.Params = append(s.Params, ts)
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		s.Results = append(s.Results, typeSchema(t.Out(i), map[reflect.Type]bool{}))
+	}
+	return s
+}
+
+// Schemas returns the Schemas of the registered functions, by name
+func Schemas() []Schema {
+	var s []Schema
+	for _, f := range forks {
+		s = append(s, f.Schema())
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Name < s[j].Name })
+	return s
+}
+
+// typeSchema describes t; types already described on the way down, in seen, aren't described again
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) TypeSchema {
+	ts := TypeSchema{
+		Type:    t.String(),
+		Kind:    t.Kind().String(),
+		Nilable: nilable(t),
+		Secret:  secretTypes[t],
+		Conn:    connTypes[t],
+	}
+	if seen[t] || ts.Conn {
+		return ts
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	switch t.Kind() {
+	case reflect.Map:
+		key := typeSchema(t.Key(), seen)
+		ts.Key = &key
+		fallthrough
+	case reflect.Array, reflect.Chan, reflect.Ptr, reflect.Slice:
+		elem := typeSchema(t.Elem(), seen)
+		ts.Elem = &elem
+		if t.Kind() == reflect.Array {
+			ts.Len = t.Len()
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				// unexported
+				continue
+			}
+			ts.Fields = append(ts.Fields, FieldSchema{Name: sf.Name, TypeSchema: typeSchema(sf.Type, seen)})
+		}
+	}
+	return ts
+}
@@ -0,0 +1,67 @@
+package fork
+
+import (
+	"reflect"
+)
+
+const redacted = "[redacted]"
+
+// SecretString is a string argument that is redacted everywhere but in the child:
+// it formats as "[redacted]" (and marshals so as JSON), and it is left out of recordings and the ArgDigest of audit records.
+// It is passed to the child, which decodes it as usual, in full.
+type SecretString string
+
+func (SecretString) String() string   { return redacted }
+func (SecretString) GoString() string { return redacted }
+
+// MarshalJSON keeps the secret out of JSON
+func (SecretString) MarshalJSON() ([]byte, error) { return []byte(`"` + redacted + `"`), nil }
+
+// SecretBytes is a []byte argument that is redacted everywhere but in the child, like SecretString.
+type SecretBytes []byte
+
+func (SecretBytes) String() string   { return redacted }
+func (SecretBytes) GoString() string { return redacted }
+
+// MarshalJSON keeps the secret out of JSON
+func (SecretBytes) MarshalJSON() ([]byte, error) { return []byte(`"` + redacted + `"`), nil }
+
+var secretTypes = map[reflect.Type]bool{
+	reflect.TypeOf(SecretString("")): true,
+	reflect.TypeOf(SecretBytes(nil)): true,
+}
+
+// secret reports whether parameter i of f is a secret
+func (f *Function) secret(i int) bool {
+	if secretTypes[f.fn.Type().In(i)] {
+		return true
+	}
+	for _, s := range f.SecretArgs {
+		if s == i {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSecrets reports whether any of the parameters of f are secrets
+func (f *Function) hasSecrets() bool {
+	for i := 0; i < f.fn.Type().NumIn(); i++ {
+		if f.secret(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns args with the secrets replaced by zero values
+func (f *Function) redact(args []interface{}) []interface{} {
+	t := f.fn.Type()
+	out := append([]interface{}(nil), args...)
+	for i := range out {
+		if f.secret(i) {
+			out[i] = reflect.Zero(t.In(i)).Interface()
+		}
+	}
+	return out
+}
@@ -0,0 +1,256 @@
+package fork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// how much of a job's stdout and stderr we keep for its JobResult
+	jobOutputMax = 1 << 20
+	// how long a finished job is kept for Result
+	jobResultTTL = time.Hour
+)
+
+// Serve makes this process a job server: the registered Functions are forked as jobs on request over JSON-RPC,
+// as the "Jobs" service with the methods Submit, Status, Cancel and Result, on addr. Schema and Schemas describe
+// the registered Functions, for clients to build their arguments.
+// An addr with a "/" in it is a unix socket, otherwise it is a TCP address.
+// Jobs are forked just as the registered Function would be, with all of its settings.
+// A finished job is forgotten once its Result has been fetched, or after an hour.
+// Serve returns when accepting a connection fails.
+//
+// Anyone who can connect to addr can run the registered functions with arguments of their choosing:
+// it should only be reachable by those trusted to.
+func Serve(addr string) error {
+	network := "tcp"
+	if strings.Contains(addr, "/") {
+		network = "unix"
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	srv := rpc.NewServer()
+	if err = srv.RegisterName("Jobs", &jobServer{jobs: map[string]*job{}}); err != nil {
+		return err
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// JobRequest is the argument of Jobs.Submit: the name of a registered Function, and its arguments, one JSON value each
+type JobRequest struct {
+	Name string            `json:"name"`
+	Args []json.RawMessage `json:"args"`
+}
+
+// JobStatus is the result of Jobs.Status
+type JobStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// State is "running", "done", "failed" or "cancelled"
+	State string `json:"state"`
+	// Error is why the job failed, if it did
+	Error string `json:"error,omitempty"`
+	// Report is the Report of the child, once it has exited
+	Report *Report `json:"report,omitempty"`
+}
+
+// JobResult is the result of Jobs.Result: the status of the finished job, and the end of its output
+type JobResult struct {
+	JobStatus
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
+// jobServer is the "Jobs" JSON-RPC service of Serve
+type jobServer struct {
+	mu   sync.Mutex
+	next int
+	jobs map[string]*job
+}
+
+// job is a fork started by Submit
+type job struct {
+	f              *Function
+	stdout, stderr *tailBuffer
+	done           chan struct{}
+	mu             sync.Mutex
+	status         JobStatus
+}
+
+// Submit forks the registered Function req.Name with req.Args, and replies with the id of the job
+func (s *jobServer) Submit(req JobRequest, id *string) error {
+	reg, ok := forks[req.Name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, req.Name)
+	}
+	args, err := jobArgs(reg, req.Args)
+	if err != nil {
+		return err
+	}
+	// a Function runs one child at a time, so each job gets its own, launched just as the registered one is
+	f := reg.clone()
+	j := &job{
+		f:      f,
+		stdout: &tailBuffer{max: jobOutputMax},
+		stderr: &tailBuffer{max: jobOutputMax},
+		done:   make(chan struct{}),
+	}
+	f.StdoutStream, f.StderrStream = j.stdout, j.stderr
+	if err = f.Fork(args...); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.next++
+	*id = strconv.Itoa(s.next)
+	j.status = JobStatus{ID: *id, Name: req.Name, State: "running"}
+	s.jobs[*id] = j
+	s.mu.Unlock()
+	jid := *id
+	go func() {
+		j.wait()
+		time.AfterFunc(jobResultTTL, func() { s.forget(jid) })
+	}()
+	return nil
+}
+
+// Status replies with the status of the job id
+func (s *jobServer) Status(id string, reply *JobStatus) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+	*reply = j.getStatus()
+	return nil
+}
+
+// Cancel kills the job id, if it is still running
+func (s *jobServer) Cancel(id string, reply *bool) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.State != "running" {
+		return nil
+	}
+	p := j.f.Command.Process
+	if p == nil {
+		return errors.New("job can't be cancelled: it runs in process")
+	}
+	if err = p.Kill(); err != nil {
+		return err
+	}
+	j.status.State = "cancelled"
+	*reply = true
+	return nil
+}
+
+// Result replies with the result of the job id once it has finished, and forgets it
+func (s *jobServer) Result(id string, reply *JobResult) error {
+	j, err := s.job(id)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-j.done:
+	default:
+		return errors.New("job is still running: " + id)
+	}
+	*reply = JobResult{JobStatus: j.getStatus(), Stdout: j.stdout.String(), Stderr: j.stderr.String()}
+	s.forget(id)
+	return nil
+}
+
+// forget drops the job id
+func (s *jobServer) forget(id string) {
+	s.mu.Lock()
+	delete(s.jobs, id)
+	s.mu.Unlock()
+}
+
+// Schema replies with the Schema of the registered Function name
+func (s *jobServer) Schema(name string, reply *Schema) error {
+	f, ok := forks[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, name)
+	}
+	*reply = f.Schema()
+	return nil
+}
+
+// Schemas replies with the Schemas of all registered Functions
+func (s *jobServer) Schemas(_ struct{}, reply *[]Schema) error {
+	*reply = Schemas()
+	return nil
+}
+
+func (s *jobServer) job(id string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.New("no job by id: " + id)
+	}
+	return j, nil
+}
+
+// wait waits for the child of j, and records how it went
+func (j *job) wait() {
+	defer close(j.done)
+	err := j.f.Wait()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Report = j.f.Report()
+	if j.status.State == "cancelled" {
+		return
+	}
+	j.status.State = "done"
+	if err != nil {
+		j.status.State, j.status.Error = "failed", err.Error()
+	}
+}
+
+func (j *job) getStatus() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// jobArgs decodes the JSON arguments of a job, to the parameter types of f
+func jobArgs(f *Function, raw []json.RawMessage) ([]interface{}, error) {
+	t := f.fn.Type()
+	if len(raw) != t.NumIn() {
+		return nil, fmt.Errorf("%w for: %s", ErrBadArgCount, t.String())
+	}
+	args := make([]interface{}, len(raw))
+	for i, r := range raw {
+		if connTypes[t.In(i)] {
+			return nil, fmt.Errorf("arg %d is a connection, which can't be passed to a job", i+1)
+		}
+		v := reflect.New(t.In(i))
+		if err := json.Unmarshal(r, v.Interface()); err != nil {
+			return nil, fmt.Errorf("arg %d: %w", i+1, err)
+		}
+		args[i] = v.Elem().Interface()
+	}
+	return args, nil
+}
@@ -0,0 +1,64 @@
+package fork
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Shutdown stops the child gracefully, and waits for it in place of Wait.
+// The child is sent SIGTERM, and given until ctx is done to exit; if it hasn't by then it is killed, and Shutdown
+// returns ErrShutdownTimeout. Otherwise, it returns what Wait would.
+// On Windows, which has no SIGTERM, the child is killed straight away.
+func (f *Function) Shutdown(ctx context.Context) error {
+	if f.local != nil {
+		// we forked in process, and can only wait
+		select {
+		case err := <-f.local:
+			f.local = nil
+			atomic.StoreInt32(&f.state, stateIdle)
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrShutdownTimeout, ctx.Err())
+		}
+	}
+	if f.Command.Process == nil {
+		return f.Wait()
+	}
+	p := f.Command.Process
+	if runtime.GOOS == "windows" || p.Signal(syscall.SIGTERM) != nil {
+		p.Kill()
+		return f.Wait()
+	}
+	waited := make(chan error, 1)
+	go func() { waited <- f.Wait() }()
+	select {
+	case err := <-waited:
+		return err
+	case <-ctx.Done():
+	}
+	f.setKilled(fmt.Errorf("%w: %v", ErrShutdownTimeout, ctx.Err()))
+	p.Kill()
+	return <-waited
+}
+
+// ShutdownAll shuts down the children of fs at once, as Shutdown does, sharing the one deadline of ctx.
+// It returns their Reports, and what Shutdown returned for each, in order; a child that never started has a nil Report.
+func ShutdownAll(ctx context.Context, fs ...*Function) (reports []*Report, errs []error) {
+	reports = make([]*Report, len(fs))
+	errs = make([]error, len(fs))
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func(i int, f *Function) {
+			defer wg.Done()
+			errs[i] = f.Shutdown(ctx)
+			reports[i] = f.Report()
+		}(i, f)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,125 @@
+package fork
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// A Snapshot is a named, content-addressed Profile: a frozen copy of the settings a Function sets up its children with,
+// its sandbox (mounts, network, ...), limits and environment. Snapshots of the same settings have the same ID,
+// and are the same Snapshot, so they can be looked up by ID and applied to any number of Functions.
+// Only the settings are shared: each child still has its environment set up on its own, mounts and all.
+// Changing the Function it was taken from doesn't change the Snapshot.
+//
+// Snapshots are kept, for LookupSnapshot, until they are released with Release.
+type Snapshot struct {
+	id string
+	p  Profile
+}
+
+var (
+	snapshotsMu sync.Mutex
+	snapshots   = map[string]*Snapshot{}
+)
+
+// Snapshot takes a Snapshot of the child environment of f
+func (f *Function) Snapshot() (*Snapshot, error) {
+	return newSnapshot(f.profile())
+}
+
+// LoadSnapshot returns the Snapshot encoded in b by its MarshalJSON
+func LoadSnapshot(b []byte) (*Snapshot, error) {
+	var p Profile
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("bad snapshot: %w", err)
+	}
+	return newSnapshot(p)
+}
+
+// LookupSnapshot returns the Snapshot by id taken, or loaded, in this process and not released, or nil if there isn't one
+func LookupSnapshot(id string) *Snapshot {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	return snapshots[id]
+}
+
+// newSnapshot returns the Snapshot of p, which is copied through its encoding so nothing is shared with the caller
+func newSnapshot(p Profile) (*Snapshot, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	id := hex.EncodeToString(sum[:])
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if s, ok := snapshots[id]; ok {
+		return s, nil
+	}
+	s := &Snapshot{id: id}
+	if err := json.Unmarshal(b, &s.p); err != nil {
+		return nil, err
+	}
+	snapshots[id] = s
+	return s, nil
+}
+
+// ID returns the hex SHA-256 of the encoded Snapshot
+func (s *Snapshot) ID() string {
+	return s.id
+}
+
+// MarshalJSON encodes the Snapshot, for LoadSnapshot
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.p)
+}
+
+// Release forgets s, so LookupSnapshot no longer finds it, and it can be collected once it isn't used.
+// Taking or loading a Snapshot of the same settings again makes a new one.
+func (s *Snapshot) Release() {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if snapshots[s.id] == s {
+		delete(snapshots, s.id)
+	}
+}
+
+// Apply applies s to f, where f doesn't have settings of its own, as Profile.Apply does.
+// The Functions s is applied to share its settings, which must not be changed in place.
+func (s *Snapshot) Apply(f *Function) {
+	s.p.Apply(f)
+}
+
+// profile returns the settings of f that make up a Profile
+func (f *Function) profile() Profile {
+	return Profile{
+		Sandbox: Sandbox{
+			Pledge:          f.Pledge,
+			Unveil:          f.Unveil,
+			Jail:            f.Jail,
+			Capsicum:        f.Capsicum,
+			Landlock:        f.Landlock,
+			Mounts:          f.Mounts,
+			Network:         f.Network,
+			InitShim:        f.InitShim,
+			Hostname:        f.Hostname,
+			PrivateTmp:      f.PrivateTmp,
+			SELinuxLabel:    f.SELinuxLabel,
+			AppArmorProfile: f.AppArmorProfile,
+		},
+		Limits: Limits{
+			MemoryLimit:        f.MemoryLimit,
+			MemoryPollInterval: f.MemoryPollInterval,
+			CPULimit:           f.CPULimit,
+			MaxArgBytes:        f.MaxArgBytes,
+			GOMAXPROCS:         f.GOMAXPROCS,
+			GOGC:               f.GOGC,
+			CoreDumps:          f.CoreDumps,
+			CPUAffinity:        f.CPUAffinity,
+		},
+		Env: f.Env,
+	}
+}
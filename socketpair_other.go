@@ -0,0 +1,13 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package fork
+
+import (
+	"errors"
+	"os"
+)
+
+func socketpair() (a, b *os.File, err error) {
+	return nil, nil, errors.New("control channels are not supported on this platform")
+}
@@ -0,0 +1,25 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package fork
+
+import (
+	"os"
+	"syscall"
+)
+
+// socketpair returns a connected pair of unix stream sockets
+func socketpair() (a, b *os.File, err error) {
+	// so no other fork inherits them before they are close-on-exec, as os.Pipe does where there is no pipe2
+	syscall.ForkLock.RLock()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err == nil {
+		syscall.CloseOnExec(fds[0])
+		syscall.CloseOnExec(fds[1])
+	}
+	syscall.ForkLock.RUnlock()
+	if err != nil {
+		return nil, nil, os.NewSyscallError("socketpair", err)
+	}
+	return os.NewFile(uintptr(fds[0]), "gofork-control"), os.NewFile(uintptr(fds[1]), "gofork-control"), nil
+}
@@ -0,0 +1,10 @@
+package fork
+
+import (
+	"errors"
+	"os"
+)
+
+func socketpair() (a, b *os.File, err error) {
+	return nil, nil, errors.New("control channels are not supported on Windows")
+}
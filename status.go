@@ -0,0 +1,234 @@
+package fork
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"net/rpc"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	statusVar = "GOFORK_STATUS"
+
+	// how long a child started from another Executable has to acknowledge its invocation
+	ackTimeout = 10 * time.Second
+)
+
+// statusMessage is sent by a child to its parent over the status pipe
+type statusMessage struct {
+	// Ack is the name of the function the child was invoked for, sent first once it found the function registered
+	// and understood the protocol, Protocol, it was invoked with
+	Ack      string
+	Protocol int
+	// Err says why the child couldn't call its function
+	Err string
+	// Topic and Payload are a message for the parent's Bus, or a subscription to Topic if Subscribe is set
+	Topic     string
+	Payload   []byte
+	Subscribe bool
+	// Ready says the child decoded its arguments, and is calling its function
+	Ready bool
+	// Panic says the function is panicking
+	Panic bool
+	// Checkpoint is the encoded state the child has got to (see Checkpoint)
+	Checkpoint []byte
+}
+
+// ChildError is returned by Wait when the child failed before it could call its function,
+// e.g. because it couldn't decode its arguments.
+type ChildError struct {
+	Message string
+}
+
+func (e *ChildError) Error() string {
+	return "child: " + e.Message
+}
+
+// Is makes a ChildError match ErrChildStart
+func (e *ChildError) Is(target error) bool {
+	return target == ErrChildStart
+}
+
+// the status pipe to our parent, if we are a child, and the encoder for it
+var (
+	status    *os.File
+	statusMu  sync.Mutex
+	statusEnc *gob.Encoder
+)
+
+// openStatus sets up the status pipe to a child that's about to start.
+// Descriptors can't be passed on Windows, so there we go without.
+func (f *Function) openStatus() (w *os.File, err error) {
+	f.status = nil
+	if runtime.GOOS == "windows" {
+		return
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return
+	}
+	f.Command.Env = append(f.Command.Env, statusVar+"="+strconv.Itoa(f.passFile(w)))
+	f.status = &statusReader{r: r, done: make(chan struct{}), ready: make(chan struct{}), ack: make(chan *statusMessage, 1)}
+	return
+}
+
+// statusReader collects the messages a child sends over the status pipe, until the child exits
+type statusReader struct {
+	r    *os.File
+	done chan struct{}
+	err  string
+	// ready is closed when the child reports it is ready
+	ready chan struct{}
+	// panicked is set when the child reports its function panicked
+	panicked bool
+	// ack gets the child's acknowledgement
+	ack chan *statusMessage
+	// the Bus the child is attached to, and the control channel to deliver it messages on
+	bus     *Bus
+	control *rpc.Client
+	// checkpoint keeps the states the child sends
+	checkpoint func([]byte)
+}
+
+func (s *statusReader) read() {
+	defer close(s.done)
+	defer s.r.Close()
+	if s.bus != nil {
+		defer s.bus.drop(s)
+	}
+	dec := gob.NewDecoder(s.r)
+	for {
+		var m statusMessage
+		if err := dec.Decode(&m); err != nil {
+			return
+		}
+		switch {
+		case m.Ack != "":
+			select {
+			case s.ack <- &m:
+			default:
+			}
+		case m.Err != "":
+			s.err = m.Err
+		case m.Ready:
+			close(s.ready)
+		case m.Panic:
+			s.panicked = true
+		case m.Checkpoint != nil:
+			s.checkpoint(m.Checkpoint)
+		case m.Topic != "" && s.bus != nil:
+			s.bus.message(s, &m)
+		}
+	}
+}
+
+// wait waits for the child's end of the status pipe to close, and returns the error it reported, if any
+func (s *statusReader) wait() error {
+	<-s.done
+	if s.err != "" {
+		return &ChildError{Message: s.err}
+	}
+	return nil
+}
+
+// Ready waits until the child has decoded its arguments, applied its settings, and is about to call its function,
+// so failures to start the child (see ChildError) can be told from those of the function, and dependent children
+// can be started in order. If the child exits before it is ready, Ready returns its ChildError, or ErrNotReady.
+// It returns ctx.Err() if ctx is done first.
+// A function forked in process, or a child on Windows, where there is no status pipe, is ready once Fork returns.
+func (f *Function) Ready(ctx context.Context) error {
+	s := f.status
+	if f.local != nil || s == nil {
+		return nil
+	}
+	select {
+	case <-s.ready:
+		return nil
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-s.ready:
+		// it got ready just before it exited
+		return nil
+	default:
+	}
+	if s.err != "" {
+		return &ChildError{Message: s.err}
+	}
+	return ErrNotReady
+}
+
+// awaitAck waits for the child to acknowledge its invocation, which a binary that doesn't link this package,
+// or doesn't call Init, never does. A child that doesn't have the function, or doesn't understand the protocol,
+// fails instead, with a ChildError. The child is left for the caller to kill.
+func (f *Function) awaitAck() error {
+	s := f.status
+	if s == nil {
+		return nil
+	}
+	t := time.NewTimer(ackTimeout)
+	defer t.Stop()
+	var ack *statusMessage
+	select {
+	case ack = <-s.ack:
+	case <-s.done:
+		// it may have acknowledged just before it exited
+		select {
+		case ack = <-s.ack:
+		default:
+			if s.err != "" {
+				return &ChildError{Message: s.err}
+			}
+			return fmt.Errorf("%w: %s exited without acknowledging its invocation", ErrNoHandshake, f.Command.Path)
+		}
+	case <-t.C:
+		return fmt.Errorf("%w: %s didn't acknowledge its invocation within %v", ErrNoHandshake, f.Command.Path, ackTimeout)
+	}
+	if ack.Ack != f.Name || ack.Protocol != ProtocolVersion {
+		return fmt.Errorf("%w: %s was invoked for %q with protocol %d, but acknowledged %q with protocol %d",
+			ErrNoHandshake, f.Command.Path, f.Name, ProtocolVersion, ack.Ack, ack.Protocol)
+	}
+	return nil
+}
+
+// initStatus picks up the status pipe from our parent
+func initStatus() {
+	v := os.Getenv(statusVar)
+	if v == "" {
+		return
+	}
+	os.Unsetenv(statusVar)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return
+	}
+	// don't leak it to anything the function starts
+	closeOnExec(fd)
+	status = os.NewFile(uintptr(fd), "gofork-status")
+	statusEnc = gob.NewEncoder(status)
+}
+
+// sendStatus sends m to our parent, or returns ErrNoControl if we have no status pipe
+func sendStatus(m *statusMessage) error {
+	if status == nil {
+		return ErrNoControl
+	}
+	// one encoder for the stream, the decoder only takes type definitions once
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return statusEnc.Encode(m)
+}
+
+// fail reports why we can't call our function to our parent, then panics with msg
+func fail(report, msg string) {
+	sendStatus(&statusMessage{Err: report})
+	stopManagement()
+	panic(msg)
+}
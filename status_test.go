@@ -0,0 +1,53 @@
+package fork
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func statusChild(n int) {}
+
+func init() {
+	RegisterFunc("statusChild", statusChild)
+}
+
+func TestChildErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		f    *Function
+		args []interface{}
+	}{
+		{"unknown function", NewFork("statusUnknown", statusChild), []interface{}{1}},
+		// the child decodes a string where it wants an int
+		{"bad argument", NewFork("statusChild", func(string) {}), []interface{}{"one"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.f.Fork(tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.f.Ready(context.Background()); !errors.Is(err, ErrChildStart) {
+				t.Errorf("Ready: got %v, want a ChildError", err)
+			}
+			err := tt.f.Wait()
+			var ce *ChildError
+			if !errors.As(err, &ce) || !errors.Is(err, ErrChildStart) {
+				t.Fatalf("Wait: got %v, want a ChildError", err)
+			}
+		})
+	}
+}
+
+func TestReady(t *testing.T) {
+	f := NewFork("statusChild", statusChild)
+	if err := f.Fork(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Ready(context.Background()); err != nil {
+		t.Errorf("Ready: %v", err)
+	}
+	if err := f.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+package fork
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// StdinMux shares one input, typically os.Stdin, between several children.
+// Input is broadcast to all attached children, or routed only to the focused one.
+//
+// A child that isn't reading its input holds up the others once its pipe fills.
+type StdinMux struct {
+	r        io.Reader
+	mu       sync.Mutex
+	children map[*Function]*os.File
+	focus    *Function
+	once     sync.Once
+	closed   bool
+}
+
+// NewStdinMux creates a StdinMux reading from r.
+// Reading starts with the first Attach, and stops at the end of r, when all children get EOF.
+func NewStdinMux(r io.Reader) *StdinMux {
+	return &StdinMux{r: r, children: make(map[*Function]*os.File)}
+}
+
+// Attach makes the mux the stdin of f, replacing Stdin.
+// It must be called before each Fork of f; the child is detached again once it stops reading.
+func (m *StdinMux) Attach(f *Function) error {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if m.closed {
+		pw.Close()
+	} else {
+		if old, ok := m.children[f]; ok {
+			old.Close()
+		}
+		m.children[f] = pw
+	}
+	m.mu.Unlock()
+	f.Stdin = pr
+	// the child's end is ours to close once it has it, so writes fail when it exits
+	f.closeAfterStart = append(f.closeAfterStart, pr)
+	m.once.Do(func() { go m.copy() })
+	return nil
+}
+
+// Detach stops sending input to f, which gets EOF
+func (m *StdinMux) Detach(f *Function) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.detach(f)
+}
+
+func (m *StdinMux) detach(f *Function) {
+	if pw, ok := m.children[f]; ok {
+		pw.Close()
+		delete(m.children, f)
+	}
+	if m.focus == f {
+		m.focus = nil
+	}
+}
+
+// Focus sends input to f only, until Broadcast or another Focus.
+func (m *StdinMux) Focus(f *Function) {
+	m.mu.Lock()
+	m.focus = f
+	m.mu.Unlock()
+}
+
+// Broadcast sends input to all attached children (the default)
+func (m *StdinMux) Broadcast() {
+	m.Focus(nil)
+}
+
+// Close detaches all children
+func (m *StdinMux) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for f := range m.children {
+		m.detach(f)
+	}
+	m.closed = true
+}
+
+func (m *StdinMux) copy() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := m.r.Read(buf)
+		if n > 0 {
+			m.write(buf[:n])
+		}
+		if err != nil {
+			m.Close()
+			return
+		}
+	}
+}
+
+func (m *StdinMux) write(p []byte) {
+	type target struct {
+		f  *Function
+		pw *os.File
+	}
+	// not holding the lock while a child's pipe is full, so Detach and Focus don't wait on it
+	var targets []target
+	m.mu.Lock()
+	for f, pw := range m.children {
+		if m.focus == nil || f == m.focus {
+			targets = append(targets, target{f, pw})
+		}
+	}
+	m.mu.Unlock()
+	for _, t := range targets {
+		if _, err := t.pw.Write(p); err != nil {
+			// the child exited, or closed its stdin, or was detached meanwhile
+			m.mu.Lock()
+			if m.children[t.f] == t.pw {
+				m.detach(t.f)
+			}
+			m.mu.Unlock()
+		}
+	}
+}
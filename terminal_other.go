@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package fork
+
+import "errors"
+
+type terminal struct{}
+
+func (f *Function) takeTerminal() error {
+	return errors.New("foreground children are not supported on this platform")
+}
+
+func (f *Function) restoreTerminal() {}
+
+func (f *Function) watchTerminal() {}
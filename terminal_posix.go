@@ -0,0 +1,103 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package fork
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// terminal is the controlling terminal, while a Foreground child has it
+type terminal struct {
+	tty     *os.File
+	pgrp    int
+	termios syscall.Termios
+	// watched is closed once the child has exited, and its stops are no longer watched for
+	watched chan struct{}
+}
+
+// takeTerminal arranges for the child to start as the foreground process group of our controlling terminal
+func (f *Function) takeTerminal() (err error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("foreground: %w", err)
+	}
+	t := &terminal{tty: tty, pgrp: syscall.Getpgrp()}
+	if err = ioctl(tty.Fd(), ioctlGetTermios, unsafe.Pointer(&t.termios)); err != nil {
+		tty.Close()
+		return fmt.Errorf("foreground: %w", err)
+	}
+	attr := syscall.SysProcAttr{}
+	if f.Command.SysProcAttr != nil {
+		attr = *f.Command.SysProcAttr
+	}
+	attr.Setpgid = true
+	attr.Pgid = 0
+	attr.Foreground = true
+	attr.Ctty = int(tty.Fd())
+	f.Command.SysProcAttr = &attr
+	f.terminal = t
+	return
+}
+
+// restoreTerminal takes the terminal back from the child, which has exited or failed to start, along with its settings
+func (f *Function) restoreTerminal() {
+	t := f.terminal
+	if t == nil {
+		return
+	}
+	f.terminal = nil
+	if t.watched != nil {
+		<-t.watched
+	}
+	t.give(t.pgrp, &t.termios)
+	t.tty.Close()
+}
+
+// watchTerminal watches for the child stopping (on ^Z, say) once it has started, which would leave a shell that started
+// us without its terminal: the terminal is taken back, and we stop ourselves with SIGTSTP, so the shell sees its job
+// stop. Once we are continued in the foreground, the child gets the terminal back, and is continued too.
+// Stops aren't watched for on the BSDs and macOS, where a stopped child keeps the terminal.
+func (f *Function) watchTerminal() {
+	t := f.terminal
+	if t == nil {
+		return
+	}
+	t.watched = make(chan struct{})
+	pid := f.Process.Pid
+	go func() {
+		defer close(t.watched)
+		for waitStopped(pid) {
+			var termios syscall.Termios
+			ioctl(t.tty.Fd(), ioctlGetTermios, unsafe.Pointer(&termios))
+			t.give(t.pgrp, &t.termios)
+			syscall.Kill(os.Getpid(), syscall.SIGTSTP)
+			var fg int32
+			if ioctl(t.tty.Fd(), syscall.TIOCGPGRP, unsafe.Pointer(&fg)) == nil && int(fg) == t.pgrp {
+				t.give(pid, &termios)
+			}
+			syscall.Kill(-pid, syscall.SIGCONT)
+		}
+	}()
+}
+
+// give makes pgrp the foreground process group of the terminal, with settings termios
+func (t *terminal) give(pgrp int, termios *syscall.Termios) {
+	// we may be in the background, and would be stopped for touching the terminal
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	p := int32(pgrp)
+	ioctl(t.tty.Fd(), syscall.TIOCSPGRP, unsafe.Pointer(&p))
+	ioctl(t.tty.Fd(), ioctlSetTermios, unsafe.Pointer(termios))
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, e := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); e != 0 {
+		return e
+	}
+	return nil
+}
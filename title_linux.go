@@ -0,0 +1,61 @@
+package fork
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const prSetName = 15
+
+// SetProcessTitle sets the title of this process, as shown by ps and top, overwriting the command line it was started with
+// (and so os.Args).
+// The title is cut short to the space the original command line took, and the command name (comm) keeps only its first 15 bytes (Linux only).
+func SetProcessTitle(title string) error {
+	setArgv(title)
+	return setComm(title)
+}
+
+// setComm sets the command name of the process, which the kernel keeps on the main thread
+func setComm(title string) error {
+	if len(title) > 15 {
+		title = title[:15]
+	}
+	if err := ioutil.WriteFile("/proc/self/comm", []byte(title), 0); err == nil {
+		return nil
+	}
+	// only names the calling thread, but better than nothing
+	b := append([]byte(title), 0)
+	if _, _, e := syscall.RawSyscall(syscall.SYS_PRCTL, prSetName, uintptr(unsafe.Pointer(&b[0])), 0); e != 0 {
+		return os.NewSyscallError("prctl", e)
+	}
+	return nil
+}
+
+// setArgv overwrites the original argv strings, which /proc/self/cmdline is read from.
+// The runtime's os.Args point into the original argv, which is laid out contiguously.
+func setArgv(title string) {
+	if len(os.Args) == 0 || len(os.Args[0]) == 0 {
+		return
+	}
+	start := stringData(os.Args[0])
+	n := 0
+	for _, a := range os.Args {
+		if len(a) == 0 || uintptr(stringData(a)) != uintptr(start)+uintptr(n) {
+			// not laid out as we expect, leave the rest alone
+			break
+		}
+		n += len(a) + 1
+	}
+	area := (*[1 << 30]byte)(start)[: n-1 : n-1]
+	i := copy(area, title)
+	for ; i < len(area); i++ {
+		area[i] = 0
+	}
+}
+
+// stringData returns a pointer to the bytes of s
+func stringData(s string) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&s))
+}
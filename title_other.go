@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package fork
+
+import "errors"
+
+// SetProcessTitle sets the title of this process, as shown by ps and top, overwriting the command line it was started with
+// (and so os.Args).
+// The title is cut short to the space the original command line took, and the command name (comm) keeps only its first 15 bytes (Linux only).
+func SetProcessTitle(title string) error {
+	return errors.New("process titles are only supported on Linux")
+}
+
+func setComm(title string) error {
+	return nil
+}
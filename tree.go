@@ -0,0 +1,23 @@
+package fork
+
+import "errors"
+
+// KillTree kills the child along with its descendants, which Process.Kill leaves running, and holding on to
+// whatever they inherited from it: ports, files, the child's stdout. See ProcessGroup for how they are found.
+// The child still has to be waited for; once it has been, KillTree fails, as Process.Kill does,
+// rather than signal whatever has its pid by now.
+//
+// Linux's cgroup.kill would catch descendants that left the process group too, but needs the child in a cgroup
+// of its own, which we don't create: the child's cgroup is the parent's, and killing it would kill us.
+func (f *Function) KillTree() error {
+	if f.local != nil {
+		return errors.New("can't kill a function forked in process")
+	}
+	if f.Command.Process == nil {
+		return errors.New("child not started")
+	}
+	if f.Command.ProcessState != nil {
+		return errors.New("child already exited")
+	}
+	return f.killTree()
+}
@@ -0,0 +1,9 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package fork
+
+// processParents finds no processes, there's no /proc to find them in
+func processParents() map[int]int {
+	return nil
+}
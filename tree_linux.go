@@ -0,0 +1,39 @@
+package fork
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+)
+
+// processParents returns the parent of every process in /proc, by pid
+func processParents() map[int]int {
+	parents := map[int]int{}
+	dir, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return parents
+	}
+	for _, fi := range dir {
+		pid, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadFile("/proc/" + fi.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// pid (comm) state ppid ..., where comm may hold anything, parentheses included
+		i := bytes.LastIndexByte(b, ')')
+		if i < 0 {
+			continue
+		}
+		fields := bytes.Fields(b[i+1:])
+		if len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(string(fields[1])); err == nil {
+			parents[pid] = ppid
+		}
+	}
+	return parents
+}
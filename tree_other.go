@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package fork
+
+func (f *Function) setProcessGroup() {}
+
+// killTree only kills the child, descendants can't be found here
+func (f *Function) killTree() error {
+	return f.Command.Process.Kill()
+}
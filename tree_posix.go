@@ -0,0 +1,48 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package fork
+
+import "syscall"
+
+// setProcessGroup makes the child the leader of a new process group
+func (f *Function) setProcessGroup() {
+	attr := syscall.SysProcAttr{}
+	if f.Command.SysProcAttr != nil {
+		// copy, so we don't modify the caller's attributes
+		attr = *f.Command.SysProcAttr
+	}
+	attr.Setpgid = true
+	attr.Pgid = 0
+	f.Command.SysProcAttr = &attr
+}
+
+// killTree kills the process group the child leads, if it does, or else the child and the descendants we can find
+func (f *Function) killTree() error {
+	pid := f.Command.Process.Pid
+	if a := f.Command.SysProcAttr; a != nil && (a.Setsid || a.Setpgid && a.Pgid == 0) {
+		return syscall.Kill(-pid, syscall.SIGKILL)
+	}
+	// stop the tree first, so nothing in it can fork while we look for it, or escape by being reparented
+	syscall.Kill(pid, syscall.SIGSTOP)
+	tree := []int{pid}
+	seen := map[int]bool{pid: true}
+	for {
+		found := false
+		for child, parent := range processParents() {
+			if seen[parent] && !seen[child] {
+				syscall.Kill(child, syscall.SIGSTOP)
+				tree = append(tree, child)
+				seen[child] = true
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	for _, p := range tree[1:] {
+		syscall.Kill(p, syscall.SIGKILL)
+	}
+	return f.Command.Process.Kill()
+}
@@ -0,0 +1,85 @@
+package fork
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// default interval for polling a child's memory use
+const defaultMemoryPollInterval = 100 * time.Millisecond
+
+// startWatchdogs starts the parent-side monitors configured for f, which run until Wait is called
+func (f *Function) startWatchdogs() {
+	f.done = make(chan struct{})
+	f.setKilled(nil)
+	if f.MemoryLimit > 0 {
+		go f.watchMemory(f.Process, f.done)
+	}
+}
+
+// stopWatchdogs stops the monitors, and returns the reason they killed the child, if they did
+func (f *Function) stopWatchdogs() error {
+	if f.done != nil {
+		close(f.done)
+		f.done = nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.killed
+}
+
+func (f *Function) setKilled(err error) {
+	f.mu.Lock()
+	f.killed = err
+	f.mu.Unlock()
+}
+
+// checkWatchdogs makes sure the monitors f needs will work here, before we start a child
+func (f *Function) checkWatchdogs() error {
+	if f.MemoryLimit > 0 {
+		if _, err := processRSS(os.Getpid()); err != nil {
+			return fmt.Errorf("memory limit: %v", err)
+		}
+		if f.InitShim {
+			// the function runs below the shim, and is found through its children
+			pid := strconv.Itoa(os.Getpid())
+			if _, err := os.Stat("/proc/" + pid + "/task/" + pid + "/children"); err != nil {
+				return fmt.Errorf("memory limit: with an init shim: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// watchMemory polls the resident set size of p, killing it if it exceeds MemoryLimit.
+// With InitShim, p is the shim, and what runs below it is measured instead.
+func (f *Function) watchMemory(p *os.Process, done <-chan struct{}) {
+	interval := f.MemoryPollInterval
+	if interval <= 0 {
+		interval = defaultMemoryPollInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+		rss, err := processRSS(p.Pid)
+		if err == nil && f.InitShim {
+			rss, err = descendantsRSS(p.Pid)
+		}
+		if err != nil {
+			// the child has exited
+			return
+		}
+		if rss > f.MemoryLimit {
+			f.setKilled(fmt.Errorf("%w: rss %d bytes > limit %d bytes", ErrMemoryLimitExceeded, rss, f.MemoryLimit))
+			p.Kill()
+			return
+		}
+	}
+}